@@ -12,6 +12,7 @@ import (
 	"github.com/shwetakhatra/url-analyzer/crawl"
 	"github.com/shwetakhatra/url-analyzer/database"
 	"github.com/shwetakhatra/url-analyzer/middleware"
+	"github.com/shwetakhatra/url-analyzer/storage"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -36,6 +37,13 @@ func main() {
     }
 
 	database.Connect()
+	storage.Connect()
+
+	if path := os.Getenv("TRACKER_CATALOG"); path != "" {
+		if err := crawl.LoadTrackerCatalog(path); err != nil {
+			log.Fatalf("Failed to load tracker catalog: %v", err)
+		}
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	crawl.StartWorker(ctx)
@@ -48,9 +56,42 @@ func main() {
 	{
 		auth.POST("/urls", controllers.CreateURL)
 		auth.GET("/urls", controllers.GetAllURLs)
+		auth.GET("/urls/export", controllers.ExportURLs)
 		auth.GET("/urls/:id", controllers.GetURLByID)
+		auth.GET("/urls/:id/headings", controllers.GetURLHeadings)
+		auth.GET("/urls/:id/findings", controllers.GetURLFindings)
+		auth.GET("/urls/:id/third-parties", controllers.GetURLThirdParties)
+		auth.GET("/urls/:id/snapshots", controllers.GetURLSnapshots)
+		auth.GET("/urls/:id/snapshots/:snapshotId", controllers.GetURLSnapshot)
+		auth.GET("/urls/:id/runs", controllers.GetURLRuns)
+		auth.GET("/urls/:id/runs/diff", controllers.GetURLRunDiff)
+		auth.PUT("/urls/:id/reprocess", controllers.ReprocessURL)
+		auth.GET("/urls/:id/analyzers", controllers.GetURLAnalyzers)
+		auth.PUT("/urls/:id/analyzers", controllers.UpdateURLAnalyzers)
+		auth.GET("/urls/:id/rules", controllers.GetURLRules)
+		auth.GET("/urls/:id/request-options", controllers.GetURLRequestOptions)
+		auth.PUT("/urls/:id/request-options", controllers.UpdateURLRequestOptions)
+		auth.GET("/urls/:id/login", controllers.GetURLLoginRecipe)
+		auth.PUT("/urls/:id/login", controllers.UpdateURLLoginRecipe)
+		auth.GET("/urls/:id/profiles", controllers.GetURLProfiles)
+		auth.PUT("/urls/:id/profiles", controllers.UpdateURLProfiles)
+		auth.GET("/urls/:id/profiles/compare", controllers.CompareURLProfiles)
+		auth.GET("/profiles", controllers.GetProfiles)
+		auth.GET("/analyzers", controllers.GetAnalyzers)
+		auth.PUT("/analyzers", controllers.UpdateAnalyzers)
+		auth.GET("/rules", controllers.GetRules)
+		auth.POST("/rules", controllers.CreateRule)
+		auth.PUT("/rules/:id", controllers.UpdateRule)
+		auth.DELETE("/rules/:id", controllers.DeleteRule)
+		auth.GET("/extractors", controllers.GetExtractors)
+		auth.POST("/extractors", controllers.CreateExtractor)
+		auth.PUT("/extractors/:id", controllers.UpdateExtractor)
+		auth.DELETE("/extractors/:id", controllers.DeleteExtractor)
+		auth.GET("/third-parties", controllers.GetThirdPartyReport)
+		auth.GET("/duplicates", controllers.GetDuplicates)
 		auth.DELETE("/urls", controllers.DeleteURLs)
 		auth.PUT("/urls/requeue", controllers.RequeueURLs)
+		auth.PUT("/urls/reprocess", controllers.ReprocessURLs)
 		auth.PUT("/urls/stop", controllers.StopURLs)
 	}
 
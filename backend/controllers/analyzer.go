@@ -0,0 +1,141 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shwetakhatra/url-analyzer/crawl"
+	"github.com/shwetakhatra/url-analyzer/database"
+	"github.com/shwetakhatra/url-analyzer/models"
+	"github.com/shwetakhatra/url-analyzer/utils"
+)
+
+type analyzerState struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	HTMLOnly    bool   `json:"html_only"`
+	Enabled     bool   `json:"enabled"`
+}
+
+// GetAnalyzers lists the registered analyzers and whether the user runs them
+func GetAnalyzers(c *gin.Context) {
+	user, err := utils.GetValidUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, utils.ErrorResponse("error", err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"analyzers": analyzerStates(crawl.EnabledAnalyzers(user.AnalyzerSettings, nil)),
+		"settings":  user.AnalyzerSettings,
+	})
+}
+
+// UpdateAnalyzers changes the analyzer settings of the user. The body maps
+// analyzer names to true or false; null removes the setting.
+func UpdateAnalyzers(c *gin.Context) {
+	user, err := utils.GetValidUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, utils.ErrorResponse("error", err.Error()))
+		return
+	}
+	settings, ok := bindAnalyzerSettings(c, user.AnalyzerSettings)
+	if !ok {
+		return
+	}
+	if err := database.DB.Model(&user).Select("analyzer_settings").Updates(models.User{AnalyzerSettings: settings}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("error", "failed to update analyzer settings"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"analyzers": analyzerStates(crawl.EnabledAnalyzers(settings, nil)),
+		"settings":  settings,
+	})
+}
+
+// GetURLAnalyzers shows which analyzers run for a URL and what each of them
+// reported on the latest analysis
+func GetURLAnalyzers(c *gin.Context) {
+	url, ok := findUserURL(c)
+	if !ok {
+		return
+	}
+	user, err := utils.GetValidUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, utils.ErrorResponse("error", err.Error()))
+		return
+	}
+	var results []models.AnalyzerResult
+	if err := database.DB.Where("url_id = ?", url.ID).Find(&results).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("error", "failed to fetch analyzer results"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"analyzers": analyzerStates(crawl.EnabledAnalyzers(user.AnalyzerSettings, url.AnalyzerSettings)),
+		"settings":  url.AnalyzerSettings,
+		"results":   results,
+	})
+}
+
+// UpdateURLAnalyzers changes the analyzer settings of a URL, which take
+// precedence over those of the user. They apply from the next analysis on.
+func UpdateURLAnalyzers(c *gin.Context) {
+	url, ok := findUserURL(c)
+	if !ok {
+		return
+	}
+	user, err := utils.GetValidUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, utils.ErrorResponse("error", err.Error()))
+		return
+	}
+	settings, ok := bindAnalyzerSettings(c, url.AnalyzerSettings)
+	if !ok {
+		return
+	}
+	if err := database.DB.Model(&url).Select("analyzer_settings").Updates(models.URL{AnalyzerSettings: settings}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("error", "failed to update analyzer settings"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"analyzers": analyzerStates(crawl.EnabledAnalyzers(user.AnalyzerSettings, settings)),
+		"settings":  settings,
+	})
+}
+
+// bindAnalyzerSettings merges the settings of the request body into current
+func bindAnalyzerSettings(c *gin.Context, current map[string]bool) (map[string]bool, bool) {
+	var body map[string]*bool
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse("error", "invalid request body"))
+		return nil, false
+	}
+	settings := map[string]bool{}
+	for name, enabled := range current {
+		settings[name] = enabled
+	}
+	for name, enabled := range body {
+		if !crawl.IsAnalyzer(name) {
+			c.JSON(http.StatusBadRequest, utils.ErrorResponse("error", "unknown analyzer: "+name))
+			return nil, false
+		}
+		if enabled == nil {
+			delete(settings, name)
+		} else {
+			settings[name] = *enabled
+		}
+	}
+	return settings, true
+}
+
+func analyzerStates(enabled map[string]bool) []analyzerState {
+	states := []analyzerState{}
+	for _, a := range crawl.Analyzers() {
+		states = append(states, analyzerState{
+			Name:        a.Name(),
+			Description: a.Description(),
+			HTMLOnly:    a.HTMLOnly(),
+			Enabled:     enabled[a.Name()],
+		})
+	}
+	return states
+}
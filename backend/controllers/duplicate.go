@@ -0,0 +1,162 @@
+package controllers
+
+import (
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shwetakhatra/url-analyzer/crawl"
+	"github.com/shwetakhatra/url-analyzer/database"
+	"github.com/shwetakhatra/url-analyzer/models"
+	"github.com/shwetakhatra/url-analyzer/utils"
+)
+
+// defaultMinSimilarity is how alike the SimHash fingerprints of two pages
+// have to be for them to count as near duplicates, 0.9 allowing 6 of 64
+// bits to differ
+const defaultMinSimilarity = 0.9
+
+type duplicateMember struct {
+	ID         string  `json:"id"`
+	URL        string  `json:"url"`
+	Title      string  `json:"title"`
+	Similarity float64 `json:"similarity"`
+}
+
+type duplicateGroup struct {
+	Key     string            `json:"key,omitempty"`
+	Members []duplicateMember `json:"members"`
+}
+
+// GetDuplicates groups the analyzed URLs of the user whose main text is
+// identical or nearly so, and those sharing a title or meta description
+func GetDuplicates(c *gin.Context) {
+	user, err := utils.GetValidUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, utils.ErrorResponse("error", err.Error()))
+		return
+	}
+	minSimilarity := defaultMinSimilarity
+	if raw := c.Query("min_similarity"); raw != "" {
+		minSimilarity, err = strconv.ParseFloat(raw, 64)
+		if err != nil || minSimilarity < 0 || minSimilarity > 1 {
+			c.JSON(http.StatusBadRequest, utils.ErrorResponse("error", "min_similarity must be a number between 0 and 1"))
+			return
+		}
+	}
+	maxDistance := int(math.Floor((1-minSimilarity)*64 + 1e-9))
+
+	var urls []models.URL
+	if err := database.DB.
+		Select("id", "url", "title", "meta_description", "content_hash", "sim_hash").
+		Where("user_id = ? AND status = ?", user.ID, "done").
+		Order("url").
+		Find(&urls).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("error", "failed to fetch URLs"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"exact": groupBy(urls, func(u models.URL) string { return u.ContentHash }),
+		"near":  nearDuplicates(urls, maxDistance),
+		"titles": groupBy(urls, func(u models.URL) string {
+			return strings.ToLower(strings.Join(strings.Fields(u.Title), " "))
+		}),
+		"descriptions": groupBy(urls, func(u models.URL) string {
+			return strings.ToLower(strings.Join(strings.Fields(u.MetaDescription), " "))
+		}),
+	})
+}
+
+// groupBy returns the groups of more than one URL sharing a non-empty key
+func groupBy(urls []models.URL, key func(models.URL) string) []duplicateGroup {
+	byKey := map[string][]duplicateMember{}
+	var keys []string
+	for _, u := range urls {
+		k := key(u)
+		if k == "" {
+			continue
+		}
+		if _, ok := byKey[k]; !ok {
+			keys = append(keys, k)
+		}
+		byKey[k] = append(byKey[k], duplicateMember{ID: u.ID, URL: u.URL, Title: u.Title, Similarity: 1})
+	}
+	groups := []duplicateGroup{}
+	for _, k := range keys {
+		if len(byKey[k]) > 1 {
+			groups = append(groups, duplicateGroup{Key: k, Members: byKey[k]})
+		}
+	}
+	return groups
+}
+
+// nearDuplicates clusters URLs whose SimHash fingerprints are within
+// maxDistance bits of each other. Pages with identical text are left to the
+// exact groups, so every cluster holds at least two different texts. Each
+// member carries its similarity to the first member of its cluster.
+func nearDuplicates(urls []models.URL, maxDistance int) []duplicateGroup {
+	var pages []models.URL
+	for _, u := range urls {
+		if u.ContentHash != "" {
+			pages = append(pages, u)
+		}
+	}
+
+	// union-find over the pairs close enough to each other
+	parent := make([]int, len(pages))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	for i := range pages {
+		for j := i + 1; j < len(pages); j++ {
+			if pages[i].ContentHash == pages[j].ContentHash {
+				continue
+			}
+			if _, distance := crawl.SimHashSimilarity(pages[i].SimHash, pages[j].SimHash); distance <= maxDistance {
+				parent[find(j)] = find(i)
+			}
+		}
+	}
+
+	clusters := map[int][]int{}
+	var roots []int
+	for i := range pages {
+		root := find(i)
+		if _, ok := clusters[root]; !ok {
+			roots = append(roots, root)
+		}
+		clusters[root] = append(clusters[root], i)
+	}
+	groups := []duplicateGroup{}
+	for _, root := range roots {
+		members := clusters[root]
+		if len(members) < 2 {
+			continue
+		}
+		first := pages[members[0]]
+		group := duplicateGroup{}
+		for _, i := range members {
+			similarity, _ := crawl.SimHashSimilarity(first.SimHash, pages[i].SimHash)
+			group.Members = append(group.Members, duplicateMember{
+				ID:         pages[i].ID,
+				URL:        pages[i].URL,
+				Title:      pages[i].Title,
+				Similarity: math.Round(similarity*1000) / 1000,
+			})
+		}
+		groups = append(groups, group)
+	}
+	sort.SliceStable(groups, func(i, j int) bool { return len(groups[i].Members) > len(groups[j].Members) })
+	return groups
+}
@@ -0,0 +1,99 @@
+package controllers
+
+import (
+	"encoding/csv"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shwetakhatra/url-analyzer/database"
+	"github.com/shwetakhatra/url-analyzer/models"
+	"github.com/shwetakhatra/url-analyzer/utils"
+)
+
+// exportColumns are the URL columns of an export, before one column per
+// extractor
+var exportColumns = []struct {
+	name  string
+	value func(url models.URL) string
+}{
+	{"id", func(url models.URL) string { return url.ID }},
+	{"url", func(url models.URL) string { return url.URL }},
+	{"status", func(url models.URL) string { return url.Status }},
+	{"title", func(url models.URL) string { return url.Title }},
+	{"html_version", func(url models.URL) string { return url.HTMLVersion }},
+	{"internal_links", func(url models.URL) string { return strconv.Itoa(url.InternalLinks) }},
+	{"external_links", func(url models.URL) string { return strconv.Itoa(url.ExternalLinks) }},
+	{"broken_links", func(url models.URL) string { return strconv.Itoa(url.BrokenLinks) }},
+	{"word_count", func(url models.URL) string { return strconv.Itoa(url.WordCount) }},
+	{"seo_score", func(url models.URL) string { return strconv.Itoa(url.SEOScore) }},
+	{"security_grade", func(url models.URL) string { return url.SecurityGrade }},
+	{"updated_at", func(url models.URL) string { return url.UpdatedAt.UTC().Format("2006-01-02T15:04:05Z") }},
+}
+
+// ExportURLs writes the URLs matching the list filters as CSV, with a
+// column for every extractor of the user. Several values of a field are
+// separated by " | ", and cells a spreadsheet would take for a formula are
+// escaped.
+func ExportURLs(c *gin.Context) {
+	user, err := utils.GetValidUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, utils.ErrorResponse("error", err.Error()))
+		return
+	}
+	query, ok := filterURLs(c, user.ID)
+	if !ok {
+		return
+	}
+	var urls []models.URL
+	if err := query.Preload("ExtractedValues").Order("created_at").Find(&urls).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("error", "failed to fetch URLs"))
+		return
+	}
+	var extractors []models.Extractor
+	if err := database.DB.Where("user_id = ?", user.ID).Order("name").Find(&extractors).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("error", "failed to fetch extractors"))
+		return
+	}
+
+	header := []string{}
+	for _, column := range exportColumns {
+		header = append(header, column.name)
+	}
+	for _, extractor := range extractors {
+		header = append(header, extractor.Name)
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="urls.csv"`)
+	c.Status(http.StatusOK)
+	w := csv.NewWriter(c.Writer)
+	writeCSVRecord(w, header)
+	for _, url := range urls {
+		values := map[string][]string{}
+		for _, value := range url.ExtractedValues {
+			values[value.ExtractorID] = value.Values
+		}
+		record := []string{}
+		for _, column := range exportColumns {
+			record = append(record, column.value(url))
+		}
+		for _, extractor := range extractors {
+			record = append(record, strings.Join(values[extractor.ID], " | "))
+		}
+		writeCSVRecord(w, record)
+	}
+	w.Flush()
+}
+
+// writeCSVRecord writes a record with every cell that starts like a formula
+// prefixed with a quote, so that spreadsheets show it as text
+func writeCSVRecord(w *csv.Writer, record []string) {
+	for i, cell := range record {
+		if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+			record[i] = "'" + cell
+		}
+	}
+	w.Write(record)
+}
@@ -0,0 +1,131 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shwetakhatra/url-analyzer/crawl"
+	"github.com/shwetakhatra/url-analyzer/database"
+	"github.com/shwetakhatra/url-analyzer/models"
+	"github.com/shwetakhatra/url-analyzer/utils"
+)
+
+type extractorRequest struct {
+	Name       string `json:"name"`
+	Selector   string `json:"selector"`
+	Attribute  string `json:"attribute"`
+	Pattern    string `json:"pattern"`
+	URLPattern string `json:"url_pattern"`
+}
+
+func GetExtractors(c *gin.Context) {
+	user, err := utils.GetValidUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, utils.ErrorResponse("error", err.Error()))
+		return
+	}
+	var extractors []models.Extractor
+	if err := database.DB.Where("user_id = ?", user.ID).Order("name").Find(&extractors).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("error", "failed to fetch extractors"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"extractors": extractors})
+}
+
+// CreateExtractor adds a field extractor. Its values are collected from the
+// next analysis of the URLs it applies to on.
+func CreateExtractor(c *gin.Context) {
+	user, err := utils.GetValidUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, utils.ErrorResponse("error", err.Error()))
+		return
+	}
+	extractor := models.Extractor{UserID: user.ID}
+	if !bindExtractor(c, &extractor) {
+		return
+	}
+	if err := database.DB.Create(&extractor).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("error", "failed to create extractor"))
+		return
+	}
+	c.JSON(http.StatusCreated, extractor)
+}
+
+func UpdateExtractor(c *gin.Context) {
+	extractor, ok := findUserExtractor(c)
+	if !ok {
+		return
+	}
+	if !bindExtractor(c, &extractor) {
+		return
+	}
+	if err := database.DB.Save(&extractor).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("error", "failed to update extractor"))
+		return
+	}
+	c.JSON(http.StatusOK, extractor)
+}
+
+func DeleteExtractor(c *gin.Context) {
+	extractor, ok := findUserExtractor(c)
+	if !ok {
+		return
+	}
+	if err := database.DB.Where("extractor_id = ?", extractor.ID).Delete(&models.ExtractedValue{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("error", "failed to delete extracted values"))
+		return
+	}
+	if err := database.DB.Delete(&extractor).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("error", "failed to delete extractor"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Extractor deleted successfully"})
+}
+
+// bindExtractor reads an extractor from the request body into extractor
+// and validates it. Names are unique per user since they label the values.
+func bindExtractor(c *gin.Context, extractor *models.Extractor) bool {
+	var body extractorRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse("error", "invalid request body"))
+		return false
+	}
+	extractor.Name = strings.TrimSpace(body.Name)
+	extractor.Selector = body.Selector
+	extractor.Attribute = strings.TrimSpace(body.Attribute)
+	extractor.Pattern = body.Pattern
+	extractor.URLPattern = body.URLPattern
+	if err := crawl.ValidateExtractor(*extractor); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse("error", err.Error()))
+		return false
+	}
+	var taken int64
+	if err := database.DB.Model(&models.Extractor{}).
+		Where("user_id = ? AND name = ? AND id <> ?", extractor.UserID, extractor.Name, extractor.ID).
+		Count(&taken).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("error", "failed to check extractor names"))
+		return false
+	}
+	if taken > 0 {
+		c.JSON(http.StatusConflict, utils.ErrorResponse("error", "an extractor with this name already exists"))
+		return false
+	}
+	return true
+}
+
+// findUserExtractor loads the extractor named by the :id route parameter if
+// it belongs to the current user, responding with an error otherwise
+func findUserExtractor(c *gin.Context) (models.Extractor, bool) {
+	user, err := utils.GetValidUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, utils.ErrorResponse("error", err.Error()))
+		return models.Extractor{}, false
+	}
+	var extractor models.Extractor
+	if err := database.DB.Where("id = ? AND user_id = ?", c.Param("id"), user.ID).First(&extractor).Error; err != nil {
+		c.JSON(http.StatusNotFound, utils.ErrorResponse("error", "extractor not found"))
+		return models.Extractor{}, false
+	}
+	return extractor, true
+}
@@ -0,0 +1,34 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shwetakhatra/url-analyzer/database"
+	"github.com/shwetakhatra/url-analyzer/models"
+	"github.com/shwetakhatra/url-analyzer/utils"
+)
+
+func GetURLFindings(c *gin.Context) {
+	url, ok := findUserURL(c)
+	if !ok {
+		return
+	}
+	query := database.DB.Where("url_id = ?", url.ID)
+	// each filter accepts a comma separated list, e.g. severity=error,warning
+	for _, field := range []string{"category", "severity", "code", "element"} {
+		if value := c.Query(field); value != "" {
+			query = query.Where(field+" IN ?", strings.Split(value, ","))
+		}
+	}
+	if selector := c.Query("selector"); selector != "" {
+		query = query.Where("selector LIKE ?", "%"+selector+"%")
+	}
+	var findings []models.Finding
+	if err := query.Order("category, created_at").Find(&findings).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("error", "failed to fetch findings"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"findings": findings})
+}
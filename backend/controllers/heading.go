@@ -0,0 +1,32 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shwetakhatra/url-analyzer/crawl"
+	"github.com/shwetakhatra/url-analyzer/database"
+	"github.com/shwetakhatra/url-analyzer/models"
+	"github.com/shwetakhatra/url-analyzer/utils"
+)
+
+func GetURLHeadings(c *gin.Context) {
+	url, ok := findUserURL(c)
+	if !ok {
+		return
+	}
+	var headings []models.Heading
+	if err := database.DB.Where("url_id = ?", url.ID).Order("position").Find(&headings).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("error", "failed to fetch headings"))
+		return
+	}
+	var issues []models.Finding
+	if err := database.DB.Where("url_id = ? AND category = ?", url.ID, crawl.CategoryHeadings).Find(&issues).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("error", "failed to fetch heading issues"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"outline": crawl.HeadingOutline(headings),
+		"issues":  issues,
+	})
+}
@@ -0,0 +1,76 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shwetakhatra/url-analyzer/crawl"
+	"github.com/shwetakhatra/url-analyzer/database"
+	"github.com/shwetakhatra/url-analyzer/models"
+	"github.com/shwetakhatra/url-analyzer/utils"
+)
+
+// GetURLLoginRecipe returns the login recipe of a URL without its password
+func GetURLLoginRecipe(c *gin.Context) {
+	url, ok := findUserURL(c)
+	if !ok {
+		return
+	}
+	if url.LoginRecipe == "" {
+		c.JSON(http.StatusNotFound, utils.ErrorResponse("error", "url has no login recipe"))
+		return
+	}
+	var recipe models.LoginRecipe
+	if err := utils.DecryptJSON(url.LoginRecipe, &recipe); err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("error", "failed to decrypt login recipe"))
+		return
+	}
+	recipe.Password = ""
+	for name := range recipe.Fields {
+		recipe.Fields[name] = ""
+	}
+	c.JSON(http.StatusOK, recipe)
+}
+
+// UpdateURLLoginRecipe replaces the login recipe of a URL. An empty body or
+// null removes it. It applies from the next crawl on.
+func UpdateURLLoginRecipe(c *gin.Context) {
+	url, ok := findUserURL(c)
+	if !ok {
+		return
+	}
+	var recipe *models.LoginRecipe
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&recipe); err != nil {
+			c.JSON(http.StatusBadRequest, utils.ErrorResponse("error", "invalid request body"))
+			return
+		}
+	}
+	sealed, ok := sealLoginRecipe(c, recipe)
+	if !ok {
+		return
+	}
+	if err := database.DB.Model(&url).Update("login_recipe", sealed).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("error", "failed to update login recipe"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"has_login_recipe": sealed != ""})
+}
+
+// sealLoginRecipe validates a login recipe and encrypts it for storage,
+// writing the error response itself. No recipe seals to "".
+func sealLoginRecipe(c *gin.Context, recipe *models.LoginRecipe) (string, bool) {
+	if recipe == nil {
+		return "", true
+	}
+	if err := crawl.ValidateLoginRecipe(*recipe); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse("error", err.Error()))
+		return "", false
+	}
+	sealed, err := utils.EncryptJSON(recipe)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("error", "failed to encrypt login recipe"))
+		return "", false
+	}
+	return sealed, true
+}
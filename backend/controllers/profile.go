@@ -0,0 +1,117 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shwetakhatra/url-analyzer/crawl"
+	"github.com/shwetakhatra/url-analyzer/database"
+	"github.com/shwetakhatra/url-analyzer/models"
+	"github.com/shwetakhatra/url-analyzer/utils"
+)
+
+// GetProfiles lists the user agent profiles URLs can be crawled with
+func GetProfiles(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"profiles": crawl.Profiles})
+}
+
+func GetURLProfiles(c *gin.Context) {
+	url, ok := findUserURL(c)
+	if !ok {
+		return
+	}
+	var results []models.ProfileResult
+	if err := database.DB.Where("url_id = ?", url.ID).Find(&results).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("error", "failed to fetch profile results"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"profiles": url.Profiles, "results": orderProfileResults(results, url.Profiles)})
+}
+
+// UpdateURLProfiles sets the profiles a URL is crawled with from its next
+// crawl on
+func UpdateURLProfiles(c *gin.Context) {
+	url, ok := findUserURL(c)
+	if !ok {
+		return
+	}
+	var body struct {
+		Profiles []string `json:"profiles"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse("error", "invalid request body"))
+		return
+	}
+	profiles, ok := validProfiles(c, body.Profiles)
+	if !ok {
+		return
+	}
+	if err := database.DB.Model(&url).Select("profiles").Updates(models.URL{Profiles: profiles}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("error", "failed to update profiles"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"profiles": profiles})
+}
+
+// CompareURLProfiles compares the results of the profiles of a URL with
+// those of the base profile, by default the first one
+func CompareURLProfiles(c *gin.Context) {
+	url, ok := findUserURL(c)
+	if !ok {
+		return
+	}
+	var results []models.ProfileResult
+	if err := database.DB.Where("url_id = ?", url.ID).Find(&results).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("error", "failed to fetch profile results"))
+		return
+	}
+	if len(results) == 0 {
+		c.JSON(http.StatusNotFound, utils.ErrorResponse("error", "url has no profile results"))
+		return
+	}
+	comparison, err := crawl.CompareProfiles(orderProfileResults(results, url.Profiles), c.Query("base"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse("error", err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, comparison)
+}
+
+// validProfiles checks profile names and drops duplicates, writing the
+// error response itself
+func validProfiles(c *gin.Context, names []string) ([]string, bool) {
+	profiles := []string{}
+	seen := map[string]bool{}
+	for _, name := range names {
+		if _, ok := crawl.FindProfile(name); !ok {
+			c.JSON(http.StatusBadRequest, utils.ErrorResponse("error", "unknown profile: "+name))
+			return nil, false
+		}
+		if !seen[name] {
+			seen[name] = true
+			profiles = append(profiles, name)
+		}
+	}
+	return profiles, true
+}
+
+// orderProfileResults puts profile results in the order the profiles are
+// configured in, followed by results of profiles no longer configured
+func orderProfileResults(results []models.ProfileResult, profiles []string) []models.ProfileResult {
+	ordered := []models.ProfileResult{}
+	used := map[int]bool{}
+	for _, name := range profiles {
+		for i, result := range results {
+			if result.Profile == name && !used[i] {
+				ordered = append(ordered, result)
+				used[i] = true
+			}
+		}
+	}
+	for i, result := range results {
+		if !used[i] {
+			ordered = append(ordered, result)
+		}
+	}
+	return ordered
+}
@@ -0,0 +1,108 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shwetakhatra/url-analyzer/database"
+	"github.com/shwetakhatra/url-analyzer/models"
+	"github.com/shwetakhatra/url-analyzer/utils"
+	"golang.org/x/net/http/httpguts"
+)
+
+// GetURLRequestOptions describes the request options of a URL without
+// revealing any of their values
+func GetURLRequestOptions(c *gin.Context) {
+	url, ok := findUserURL(c)
+	if !ok {
+		return
+	}
+	var options models.RequestOptions
+	if url.RequestOptions != "" {
+		if err := utils.DecryptJSON(url.RequestOptions, &options); err != nil {
+			c.JSON(http.StatusInternalServerError, utils.ErrorResponse("error", "failed to decrypt request options"))
+			return
+		}
+	}
+	response := gin.H{
+		"headers":        sortedKeys(options.Headers),
+		"cookies":        sortedKeys(options.Cookies),
+		"basic_auth":     options.BasicAuth != nil,
+		"apply_to_links": options.ApplyToLinks,
+	}
+	if options.BasicAuth != nil {
+		response["username"] = options.BasicAuth.Username
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// UpdateURLRequestOptions replaces the request options of a URL. An empty
+// body or null removes them. They apply from the next crawl on.
+func UpdateURLRequestOptions(c *gin.Context) {
+	url, ok := findUserURL(c)
+	if !ok {
+		return
+	}
+	var options *models.RequestOptions
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&options); err != nil {
+			c.JSON(http.StatusBadRequest, utils.ErrorResponse("error", "invalid request body"))
+			return
+		}
+	}
+	sealed, ok := sealRequestOptions(c, options)
+	if !ok {
+		return
+	}
+	if err := database.DB.Model(&url).Update("request_options", sealed).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("error", "failed to update request options"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"has_request_options": sealed != ""})
+}
+
+// sealRequestOptions validates request options and encrypts them for
+// storage, writing the error response itself. Empty options seal to "".
+func sealRequestOptions(c *gin.Context, options *models.RequestOptions) (string, bool) {
+	if options.Empty() {
+		return "", true
+	}
+	if err := validateRequestOptions(options); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse("error", err.Error()))
+		return "", false
+	}
+	sealed, err := utils.EncryptJSON(options)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("error", "failed to encrypt request options"))
+		return "", false
+	}
+	return sealed, true
+}
+
+func validateRequestOptions(options *models.RequestOptions) error {
+	for name, value := range options.Headers {
+		if !httpguts.ValidHeaderFieldName(name) || !httpguts.ValidHeaderFieldValue(value) {
+			return errors.New("invalid header: " + name)
+		}
+	}
+	for name, value := range options.Cookies {
+		if !httpguts.ValidHeaderFieldName(name) || !httpguts.ValidHeaderFieldValue(value) {
+			return errors.New("invalid cookie: " + name)
+		}
+	}
+	if options.BasicAuth != nil && options.BasicAuth.Username == "" {
+		return errors.New("basic auth needs a username")
+	}
+	return nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := []string{}
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,179 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shwetakhatra/url-analyzer/crawl"
+	"github.com/shwetakhatra/url-analyzer/database"
+	"github.com/shwetakhatra/url-analyzer/models"
+	"github.com/shwetakhatra/url-analyzer/utils"
+)
+
+type ruleRequest struct {
+	Name       string   `json:"name"`
+	Selector   string   `json:"selector"`
+	Assertion  string   `json:"assertion"`
+	Operator   string   `json:"operator"`
+	Value      int      `json:"value"`
+	Severity   string   `json:"severity"`
+	URLPattern string   `json:"url_pattern"`
+	URLIDs     []string `json:"url_ids"`
+	Enabled    *bool    `json:"enabled"`
+}
+
+func GetRules(c *gin.Context) {
+	user, err := utils.GetValidUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, utils.ErrorResponse("error", err.Error()))
+		return
+	}
+	var rules []models.Rule
+	if err := database.DB.Where("user_id = ?", user.ID).Order("created_at").Find(&rules).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("error", "failed to fetch rules"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"rules": rules})
+}
+
+// CreateRule adds an assertion rule. It is evaluated from the next analysis
+// of the URLs it applies to on.
+func CreateRule(c *gin.Context) {
+	user, err := utils.GetValidUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, utils.ErrorResponse("error", err.Error()))
+		return
+	}
+	rule := models.Rule{UserID: user.ID}
+	if !bindRule(c, &rule) {
+		return
+	}
+	if err := database.DB.Create(&rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("error", "failed to create rule"))
+		return
+	}
+	c.JSON(http.StatusCreated, rule)
+}
+
+func UpdateRule(c *gin.Context) {
+	rule, ok := findUserRule(c)
+	if !ok {
+		return
+	}
+	if !bindRule(c, &rule) {
+		return
+	}
+	if err := database.DB.Save(&rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("error", "failed to update rule"))
+		return
+	}
+	c.JSON(http.StatusOK, rule)
+}
+
+func DeleteRule(c *gin.Context) {
+	rule, ok := findUserRule(c)
+	if !ok {
+		return
+	}
+	if err := database.DB.Where("rule_id = ?", rule.ID).Delete(&models.RuleResult{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("error", "failed to delete rule results"))
+		return
+	}
+	if err := database.DB.Delete(&rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("error", "failed to delete rule"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Rule deleted successfully"})
+}
+
+// GetURLRules lists the rules that apply to a URL and their results on its
+// latest analysis
+func GetURLRules(c *gin.Context) {
+	url, ok := findUserURL(c)
+	if !ok {
+		return
+	}
+	var rules []models.Rule
+	if err := database.DB.Where("user_id = ?", url.UserID).Order("created_at").Find(&rules).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("error", "failed to fetch rules"))
+		return
+	}
+	applicable := []models.Rule{}
+	for _, rule := range rules {
+		if crawl.RuleApplies(rule, url) {
+			applicable = append(applicable, rule)
+		}
+	}
+	var results []models.RuleResult
+	if err := database.DB.Where("url_id = ?", url.ID).Find(&results).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("error", "failed to fetch rule results"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"rules": applicable, "results": results})
+}
+
+// bindRule reads a rule from the request body into rule and validates it
+func bindRule(c *gin.Context, rule *models.Rule) bool {
+	var body ruleRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse("error", "invalid request body"))
+		return false
+	}
+	rule.Name = body.Name
+	rule.Selector = body.Selector
+	rule.Assertion = body.Assertion
+	rule.Operator = body.Operator
+	rule.Value = body.Value
+	rule.Severity = body.Severity
+	if rule.Severity == "" {
+		rule.Severity = models.SeverityWarning
+	}
+	rule.URLPattern = body.URLPattern
+	rule.URLIDs = []string{}
+	seen := map[string]bool{}
+	for _, id := range body.URLIDs {
+		if !seen[id] {
+			seen[id] = true
+			rule.URLIDs = append(rule.URLIDs, id)
+		}
+	}
+	rule.Enabled = body.Enabled == nil || *body.Enabled
+	if rule.Assertion != models.RuleCount {
+		rule.Operator = ""
+		rule.Value = 0
+	}
+	if err := crawl.ValidateRule(*rule); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse("error", err.Error()))
+		return false
+	}
+	if len(rule.URLIDs) > 0 {
+		var owned int64
+		if err := database.DB.Model(&models.URL{}).
+			Where("user_id = ? AND id IN ?", rule.UserID, rule.URLIDs).
+			Count(&owned).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, utils.ErrorResponse("error", "failed to check URLs"))
+			return false
+		}
+		if int(owned) != len(rule.URLIDs) {
+			c.JSON(http.StatusBadRequest, utils.ErrorResponse("error", "url_ids contains unknown URLs"))
+			return false
+		}
+	}
+	return true
+}
+
+// findUserRule loads the rule named by the :id route parameter if it
+// belongs to the current user, responding with an error otherwise
+func findUserRule(c *gin.Context) (models.Rule, bool) {
+	user, err := utils.GetValidUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, utils.ErrorResponse("error", err.Error()))
+		return models.Rule{}, false
+	}
+	var rule models.Rule
+	if err := database.DB.Where("id = ? AND user_id = ?", c.Param("id"), user.ID).First(&rule).Error; err != nil {
+		c.JSON(http.StatusNotFound, utils.ErrorResponse("error", "rule not found"))
+		return models.Rule{}, false
+	}
+	return rule, true
+}
@@ -0,0 +1,162 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shwetakhatra/url-analyzer/crawl"
+	"github.com/shwetakhatra/url-analyzer/database"
+	"github.com/shwetakhatra/url-analyzer/models"
+	"github.com/shwetakhatra/url-analyzer/utils"
+)
+
+func GetURLRuns(c *gin.Context) {
+	url, ok := findUserURL(c)
+	if !ok {
+		return
+	}
+	var runs []models.Run
+	if err := database.DB.Where("url_id = ?", url.ID).Order("version DESC").Find(&runs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("error", "failed to fetch runs"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"runs": runs})
+}
+
+// GetURLRunDiff compares two runs of a URL given by their versions. to
+// defaults to the latest successful or unchanged run and from to the one
+// before it.
+func GetURLRunDiff(c *gin.Context) {
+	url, ok := findUserURL(c)
+	if !ok {
+		return
+	}
+	var versions [2]int
+	for i, param := range []string{"from", "to"} {
+		if raw := c.Query(param); raw != "" {
+			v, err := strconv.Atoi(raw)
+			if err != nil || v < 1 {
+				c.JSON(http.StatusBadRequest, utils.ErrorResponse("error", param+" must be a run version"))
+				return
+			}
+			versions[i] = v
+		}
+	}
+
+	var runs [2]models.Run
+	for i := 1; i >= 0; i-- {
+		query := database.DB.Where("url_id = ? AND status IN ?", url.ID, []string{"done", models.RunStatusUnchanged})
+		switch {
+		case versions[i] != 0:
+			query = query.Where("version = ?", versions[i])
+		case i == 0:
+			query = query.Where("version < ?", runs[1].Version)
+		}
+		if err := query.Order("version DESC").First(&runs[i]).Error; err != nil {
+			c.JSON(http.StatusNotFound, utils.ErrorResponse("error", "run not found"))
+			return
+		}
+	}
+	c.JSON(http.StatusOK, crawl.DiffRuns(runs[0], runs[1]))
+}
+
+// ReprocessURL queues a URL for analysis of a stored snapshot, the latest
+// one unless snapshot_id names another, without fetching the page again
+func ReprocessURL(c *gin.Context) {
+	url, ok := findUserURL(c)
+	if !ok {
+		return
+	}
+	var body struct {
+		SnapshotID string `json:"snapshot_id"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, utils.ErrorResponse("error", "invalid request body"))
+			return
+		}
+	}
+	if url.Status == "running" {
+		c.JSON(http.StatusConflict, utils.ErrorResponse("error", "url is being analyzed"))
+		return
+	}
+	query := database.DB.Where("url_id = ?", url.ID)
+	if body.SnapshotID != "" {
+		query = query.Where("id = ?", body.SnapshotID)
+	}
+	var snapshot models.Snapshot
+	if err := query.Order("fetched_at DESC").First(&snapshot).Error; err != nil {
+		c.JSON(http.StatusNotFound, utils.ErrorResponse("error", "snapshot not found"))
+		return
+	}
+	queued, err := queueReprocess(url.ID, snapshot.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("error", "failed to queue reprocessing"))
+		return
+	}
+	if !queued {
+		c.JSON(http.StatusConflict, utils.ErrorResponse("error", "url is being analyzed"))
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "URL queued for reprocessing", "snapshot_id": snapshot.ID})
+}
+
+// ReprocessURLs queues the latest snapshot of several URLs, or of all URLs
+// of the user, for analysis. URLs without a snapshot or being analyzed
+// right now are skipped.
+func ReprocessURLs(c *gin.Context) {
+	user, err := utils.GetValidUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, utils.ErrorResponse("error", err.Error()))
+		return
+	}
+	var body struct {
+		IDs []string `json:"ids"`
+		All bool     `json:"all"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil || (len(body.IDs) == 0 && !body.All) {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse("error", "invalid or empty ID list"))
+		return
+	}
+	query := database.DB.Where("user_id = ?", user.ID)
+	if !body.All {
+		query = query.Where("id IN ?", body.IDs)
+	}
+	var urls []models.URL
+	if err := query.Find(&urls).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("error", "failed to fetch URLs"))
+		return
+	}
+
+	queued := 0
+	skipped := []string{}
+	for _, url := range urls {
+		var snapshot models.Snapshot
+		if url.Status == "running" ||
+			database.DB.Where("url_id = ?", url.ID).Order("fetched_at DESC").First(&snapshot).Error != nil {
+			skipped = append(skipped, url.ID)
+			continue
+		}
+		ok, err := queueReprocess(url.ID, snapshot.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, utils.ErrorResponse("error", "failed to queue reprocessing"))
+			return
+		}
+		if !ok {
+			skipped = append(skipped, url.ID)
+			continue
+		}
+		queued++
+	}
+	c.JSON(http.StatusAccepted, gin.H{"queued": queued, "skipped": skipped})
+}
+
+// queueReprocess queues a snapshot of a URL unless the URL started running
+// in the meantime, and tells whether it did
+func queueReprocess(urlID, snapshotID string) (bool, error) {
+	result := database.DB.Model(&models.URL{}).
+		Where("id = ? AND status <> ?", urlID, "running").
+		Updates(map[string]interface{}{"status": "queued", "pending_snapshot_id": snapshotID})
+	return result.RowsAffected > 0, result.Error
+}
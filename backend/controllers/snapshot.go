@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shwetakhatra/url-analyzer/crawl"
+	"github.com/shwetakhatra/url-analyzer/database"
+	"github.com/shwetakhatra/url-analyzer/models"
+	"github.com/shwetakhatra/url-analyzer/storage"
+	"github.com/shwetakhatra/url-analyzer/utils"
+)
+
+func GetURLSnapshots(c *gin.Context) {
+	url, ok := findUserURL(c)
+	if !ok {
+		return
+	}
+	var snapshots []models.Snapshot
+	if err := database.DB.Where("url_id = ?", url.ID).Order("fetched_at DESC").Find(&snapshots).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("error", "failed to fetch snapshots"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"snapshots": snapshots})
+}
+
+// GetURLSnapshot downloads an archived page, gzipped as stored. With
+// view=true it is served uncompressed and inline instead, sandboxed so that
+// its scripts cannot run. The snapshot id "latest" names the newest one.
+func GetURLSnapshot(c *gin.Context) {
+	snapshot, ok := findURLSnapshot(c)
+	if !ok {
+		return
+	}
+
+	if c.Query("view") == "true" {
+		body, err := crawl.SnapshotBody(c.Request.Context(), snapshot)
+		if err != nil {
+			snapshotError(c, err)
+			return
+		}
+		contentType := snapshot.ContentType
+		if contentType == "" {
+			contentType = "text/html"
+		}
+		c.Header("Content-Security-Policy", "sandbox")
+		c.Header("X-Content-Type-Options", "nosniff")
+		c.Data(http.StatusOK, contentType, body)
+		return
+	}
+
+	compressed, err := storage.Blobs.Get(c.Request.Context(), snapshot.BlobKey)
+	if err != nil {
+		snapshotError(c, err)
+		return
+	}
+	c.Header("Content-Disposition", `attachment; filename="`+snapshot.ID+`.gz"`)
+	c.Data(http.StatusOK, "application/gzip", compressed)
+}
+
+// findURLSnapshot loads the snapshot named by the :snapshotId path parameter
+// of a URL of the current user
+func findURLSnapshot(c *gin.Context) (models.Snapshot, bool) {
+	url, ok := findUserURL(c)
+	if !ok {
+		return models.Snapshot{}, false
+	}
+	query := database.DB.Where("url_id = ?", url.ID)
+	if id := c.Param("snapshotId"); id != "latest" {
+		query = query.Where("id = ?", id)
+	}
+	var snapshot models.Snapshot
+	if err := query.Order("fetched_at DESC").First(&snapshot).Error; err != nil {
+		c.JSON(http.StatusNotFound, utils.ErrorResponse("error", "snapshot not found"))
+		return models.Snapshot{}, false
+	}
+	return snapshot, true
+}
+
+func snapshotError(c *gin.Context, err error) {
+	if errors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusNotFound, utils.ErrorResponse("error", "snapshot content is no longer stored"))
+		return
+	}
+	c.JSON(http.StatusInternalServerError, utils.ErrorResponse("error", "failed to read snapshot"))
+}
@@ -0,0 +1,64 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shwetakhatra/url-analyzer/database"
+	"github.com/shwetakhatra/url-analyzer/models"
+	"github.com/shwetakhatra/url-analyzer/utils"
+)
+
+func GetURLThirdParties(c *gin.Context) {
+	url, ok := findUserURL(c)
+	if !ok {
+		return
+	}
+	query := database.DB.Where("url_id = ?", url.ID)
+	if c.Query("trackers") == "true" {
+		query = query.Where("tracker = ?", true)
+	}
+	var parties []models.ThirdParty
+	if err := query.Order("domain").Find(&parties).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("error", "failed to fetch third parties"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"third_parties": parties})
+}
+
+// GetThirdPartyReport aggregates the third-party domains over all URLs of the user
+func GetThirdPartyReport(c *gin.Context) {
+	user, err := utils.GetValidUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, utils.ErrorResponse("error", err.Error()))
+		return
+	}
+	var report []struct {
+		Domain   string `json:"domain"`
+		Vendor   string `json:"vendor"`
+		Category string `json:"category"`
+		Tracker  bool   `json:"tracker"`
+		URLCount int    `json:"url_count"`
+		Requests int    `json:"requests"`
+	}
+	query := database.DB.
+		Model(&models.ThirdParty{}).
+		Select("third_parties.domain, third_parties.vendor, third_parties.category, third_parties.tracker, "+
+			"COUNT(DISTINCT third_parties.url_id) AS url_count, SUM(third_parties.requests) AS requests").
+		Joins("JOIN urls ON urls.id = third_parties.url_id").
+		Where("urls.user_id = ?", user.ID)
+	if category := c.Query("category"); category != "" {
+		query = query.Where("third_parties.category = ?", category)
+	}
+	if c.Query("trackers") == "true" {
+		query = query.Where("third_parties.tracker = ?", true)
+	}
+	if err := query.
+		Group("third_parties.domain, third_parties.vendor, third_parties.category, third_parties.tracker").
+		Order("url_count DESC, third_parties.domain").
+		Scan(&report).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("error", "failed to build third-party report"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"third_parties": report})
+}
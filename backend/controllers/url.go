@@ -3,16 +3,54 @@ package controllers
 import (
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/shwetakhatra/url-analyzer/crawl"
 	"github.com/shwetakhatra/url-analyzer/database"
 	"github.com/shwetakhatra/url-analyzer/models"
 	"github.com/shwetakhatra/url-analyzer/utils"
 	"gorm.io/gorm"
 )
 
+// rows owned by a URL that have to go when the URL is deleted
+var urlDetailModels = []interface{}{
+	&models.BrokenLink{},
+	&models.Heading{},
+	&models.Finding{},
+	&models.StructuredData{},
+	&models.Certificate{},
+	&models.Resource{},
+	&models.Form{},
+	&models.ThirdParty{},
+	&models.Snapshot{},
+	&models.Run{},
+	&models.AnalyzerResult{},
+	&models.RuleResult{},
+	&models.ExtractedValue{},
+	&models.ProfileResult{},
+}
+
+// numeric range filters of the URL list: query parameter, column and comparison
+var urlRangeFilters = []struct {
+	param  string
+	column string
+	op     string
+}{
+	{"min_words", "word_count", ">="},
+	{"max_words", "word_count", "<="},
+	{"min_text_ratio", "text_ratio", ">="},
+	{"max_text_ratio", "text_ratio", "<="},
+	{"min_readability", "readability_score", ">="},
+	{"max_readability", "readability_score", "<="},
+}
+
 type CreateURLRequest struct {
-	URL string `json:"url" binding:"required,url"`
+	URL            string                 `json:"url" binding:"required,url"`
+	CheckResources bool                   `json:"check_resources"`
+	RequestOptions *models.RequestOptions `json:"request_options"`
+	Login          *models.LoginRecipe    `json:"login"`
+	Profiles       []string               `json:"profiles"`
 }
 
 func CreateURL(c *gin.Context) {
@@ -26,10 +64,28 @@ func CreateURL(c *gin.Context) {
 		c.JSON(http.StatusUnauthorized, utils.ErrorResponse("error", err.Error()))
 		return
 	}
+	sealed, ok := sealRequestOptions(c, input.RequestOptions)
+	if !ok {
+		return
+	}
+	recipe, ok := sealLoginRecipe(c, input.Login)
+	if !ok {
+		return
+	}
+	profiles, ok := validProfiles(c, input.Profiles)
+	if !ok {
+		return
+	}
 	url := models.URL{
-		URL:    input.URL,
-		Status: "queued",
-		UserID: user.ID,
+		URL:               input.URL,
+		Status:            "queued",
+		UserID:            user.ID,
+		CheckResources:    input.CheckResources,
+		RequestOptions:    sealed,
+		HasRequestOptions: sealed != "",
+		LoginRecipe:       recipe,
+		HasLoginRecipe:    recipe != "",
+		Profiles:          profiles,
 	}
 	if err := database.DB.Create(&url).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("error", "could not save URL"))
@@ -45,24 +101,24 @@ func GetAllURLs(c *gin.Context) {
 		return
 	}
 	var urls []models.URL
-	search := c.Query("search")
-	status := c.Query("status")
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
 	offset := (page - 1) * limit
 
-	query := database.DB.
-		Model(&models.URL{}).
-		Where("user_id = ?", user.ID).
+	query, ok := filterURLs(c, user.ID)
+	if !ok {
+		return
+	}
+	query = query.
 		Preload("BrokenLinkDetail", func(db *gorm.DB) *gorm.DB {
-			return db.Select("link", "status", "url_id")
+			return db.Select("link", "status", "type", "url_id")
+		}).
+		Preload("Forms", func(db *gorm.DB) *gorm.DB {
+			return db.Order("position")
+		}).
+		Preload("ExtractedValues", func(db *gorm.DB) *gorm.DB {
+			return db.Order("name")
 		})
-	if search != "" {
-		query = query.Where("url LIKE ?", "%"+search+"%")
-	}
-	if status != "" {
-		query = query.Where("status = ?", status)
-	}
 
 	// Count total matching records (without pagination)
 	var total int64
@@ -83,19 +139,98 @@ func GetAllURLs(c *gin.Context) {
 	})
 }
 
+// filterURLs builds the query for the URLs of a user matching the filters
+// of the request, writing the error response itself for invalid filters
+func filterURLs(c *gin.Context, userID string) (*gorm.DB, bool) {
+	search := c.Query("search")
+	status := c.Query("status")
+	query := database.DB.
+		Model(&models.URL{}).
+		Where("user_id = ?", userID)
+	if search != "" {
+		query = query.Where("url LIKE ?", "%"+search+"%")
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if days := c.Query("cert_expiring_within"); days != "" {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			c.JSON(http.StatusBadRequest, utils.ErrorResponse("error", "cert_expiring_within must be a number of days"))
+			return nil, false
+		}
+		query = query.Where("cert_not_after IS NOT NULL AND cert_not_after <= ?", time.Now().AddDate(0, 0, n))
+	}
+	for _, f := range urlRangeFilters {
+		raw := c.Query(f.param)
+		if raw == "" {
+			continue
+		}
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, utils.ErrorResponse("error", f.param+" must be a number"))
+			return nil, false
+		}
+		query = query.Where(f.column+" "+f.op+" ?", value)
+	}
+	return query, true
+}
+
 func GetURLByID(c *gin.Context) {
+	url, ok := findUserURL(c)
+	if !ok {
+		return
+	}
+	if err := database.DB.Where("url_id = ?", url.ID).Find(&url.StructuredData).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("error", "failed to fetch structured data"))
+		return
+	}
+	if err := database.DB.Where("url_id = ?", url.ID).Order("position").Find(&url.Certificates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("error", "failed to fetch certificates"))
+		return
+	}
+	if err := database.DB.Where("url_id = ?", url.ID).Find(&url.BrokenLinkDetail).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("error", "failed to fetch broken links"))
+		return
+	}
+	if err := database.DB.Where("url_id = ?", url.ID).Find(&url.Resources).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("error", "failed to fetch resources"))
+		return
+	}
+	if err := database.DB.Where("url_id = ?", url.ID).Order("position").Find(&url.Forms).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("error", "failed to fetch forms"))
+		return
+	}
+	if err := database.DB.Where("url_id = ?", url.ID).Order("domain").Find(&url.ThirdParties).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("error", "failed to fetch third parties"))
+		return
+	}
+	if err := database.DB.Where("url_id = ?", url.ID).Order("name").Find(&url.ExtractedValues).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("error", "failed to fetch extracted values"))
+		return
+	}
+	if err := database.DB.Where("url_id = ?", url.ID).Order("analyzer").Find(&url.AnalyzerResults).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("error", "failed to fetch analyzer results"))
+		return
+	}
+	c.JSON(http.StatusOK, url)
+}
+
+// findUserURL loads the URL named by the :id path parameter for the current
+// user, writing the error response itself when that is not possible
+func findUserURL(c *gin.Context) (models.URL, bool) {
 	user, err := utils.GetValidUser(c)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, utils.ErrorResponse("error", err.Error()))
-		return
+		return models.URL{}, false
 	}
 	id := c.Param("id")
 	var url models.URL
 	if err := database.DB.Where("id = ? AND user_id = ?", id, user.ID).First(&url).Error; err != nil {
 		c.JSON(http.StatusNotFound, utils.ErrorResponse("error", "url not found"))
-		return
+		return models.URL{}, false
 	}
-	c.JSON(http.StatusOK, url)
+	return url, true
 }
 
 func DeleteURLs(c *gin.Context) {
@@ -126,33 +261,50 @@ func DeleteURLs(c *gin.Context) {
 	for i, url := range urls {
 		idsToDelete[i] = url.ID
 	}
-	if err := database.DB.Where("url_id IN ?", idsToDelete).Delete(&models.BrokenLink{}).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("error", "failed to delete broken links"))
+	var snapshots []models.Snapshot
+	if err := database.DB.Where("url_id IN ?", idsToDelete).Find(&snapshots).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("error", "failed to fetch snapshots"))
 		return
 	}
+	crawl.DeleteSnapshots(snapshots)
+	for _, detail := range urlDetailModels {
+		if err := database.DB.Where("url_id IN ?", idsToDelete).Delete(detail).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, utils.ErrorResponse("error", "failed to delete URL details"))
+			return
+		}
+	}
 	if err := database.DB.Delete(&models.URL{}, idsToDelete).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("error", "failed to delete URLs"))
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "URLs and related broken links deleted successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": "URLs and related details deleted successfully"})
 }
 
 func RequeueURLs(c *gin.Context) {
-	_, err := utils.GetValidUser(c)
+	user, err := utils.GetValidUser(c)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, utils.ErrorResponse("error", err.Error()))
 		return
 	}
 	var body struct {
 		IDs []string `json:"ids"`
+		// Force skips the conditional request and analyzes the page even
+		// if it did not change
+		Force bool `json:"force"`
 	}
 	if err := c.ShouldBindJSON(&body); err != nil || len(body.IDs) == 0 {
 		c.JSON(http.StatusBadRequest, utils.ErrorResponse("error", "invalid or empty ID list"))
 		return
 	}
+	updates := map[string]interface{}{"status": "queued", "pending_snapshot_id": ""}
+	if body.Force {
+		updates["etag"] = ""
+		updates["last_modified"] = ""
+	}
+	// a running URL keeps its crawl, which would otherwise race a new one
 	if err := database.DB.Model(&models.URL{}).
-		Where("id IN ?", body.IDs).
-		Update("status", "queued").Error; err != nil {
+		Where("id IN ? AND user_id = ? AND status <> ?", body.IDs, user.ID, "running").
+		Updates(updates).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, utils.ErrorResponse("error", "failed to requeue URLs"))
 		return
 	}
@@ -0,0 +1,185 @@
+package crawl
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/shwetakhatra/url-analyzer/models"
+)
+
+// CategoryAccessibility groups the findings of the static accessibility audit
+const CategoryAccessibility = "accessibility"
+
+// link texts that say nothing about where the link goes
+var nonDescriptiveLinkTexts = map[string]bool{
+	"click here": true,
+	"click":      true,
+	"here":       true,
+	"more":       true,
+	"read more":  true,
+	"learn more": true,
+	"link":       true,
+	"this":       true,
+	"this link":  true,
+	"details":    true,
+	"go":         true,
+}
+
+var ariaRoles = toSet(strings.Fields(`alert alertdialog application article banner blockquote
+	button caption cell checkbox code columnheader combobox complementary contentinfo definition
+	deletion dialog directory document emphasis feed figure form generic grid gridcell group
+	heading img insertion link list listbox listitem log main marquee math meter menu menubar
+	menuitem menuitemcheckbox menuitemradio navigation none note option paragraph presentation
+	progressbar radio radiogroup region row rowgroup rowheader scrollbar search searchbox
+	separator slider spinbutton status strong subscript superscript switch tab table tablist
+	tabpanel term textbox time timer toolbar tooltip tree treegrid treeitem`))
+
+// abstract roles exist only to structure the ARIA taxonomy and must not be used by authors
+var abstractAriaRoles = toSet(strings.Fields(`command composite input landmark range roletype
+	section sectionhead select structure widget window`))
+
+// input types that need no label of their own
+var unlabeledInputTypes = map[string]bool{
+	"hidden": true,
+	"submit": true,
+	"reset":  true,
+	"button": true,
+	"image":  true,
+}
+
+// analyzeAccessibility runs static accessibility checks against the parsed page
+func analyzeAccessibility(doc *goquery.Document, urlID string, result *CrawlResult) {
+	paths := newCSSPaths(doc)
+	add := func(code, severity, message string, s *goquery.Selection) {
+		result.Findings = append(result.Findings, models.Finding{
+			URLID:    urlID,
+			Category: CategoryAccessibility,
+			Code:     code,
+			Severity: severity,
+			Message:  message,
+			Selector: paths.of(s),
+		})
+	}
+
+	if strings.TrimSpace(doc.Find("html").AttrOr("lang", "")) == "" {
+		add("missing_lang", models.SeverityError, "The html element has no lang attribute", doc.Find("html"))
+	}
+
+	doc.Find("img, area, input[type=image]").Each(func(_ int, s *goquery.Selection) {
+		if _, ok := s.Attr("alt"); ok || isHiddenFromAT(s) || hasAccessibleName(s) {
+			return
+		}
+		add("missing_alt", models.SeverityError,
+			fmt.Sprintf("<%s> has no alt text", goquery.NodeName(s)), s)
+	})
+
+	labelled := map[string]bool{}
+	doc.Find("label[for]").Each(func(_ int, s *goquery.Selection) {
+		labelled[s.AttrOr("for", "")] = true
+	})
+	doc.Find("input, select, textarea").Each(func(_ int, s *goquery.Selection) {
+		if unlabeledInputTypes[strings.ToLower(s.AttrOr("type", ""))] || isHiddenFromAT(s) {
+			return
+		}
+		if id, ok := s.Attr("id"); ok && labelled[id] {
+			return
+		}
+		if s.Closest("label").Length() > 0 || hasAccessibleName(s) {
+			return
+		}
+		add("missing_label", models.SeverityError,
+			fmt.Sprintf("<%s> has no associated label", goquery.NodeName(s)), s)
+	})
+
+	doc.Find("a[href]").Each(func(_ int, s *goquery.Selection) {
+		if isHiddenFromAT(s) || hasAccessibleName(s) {
+			return
+		}
+		text := strings.Join(strings.Fields(s.Text()), " ")
+		if text == "" {
+			s.Find("img[alt]").Each(func(_ int, img *goquery.Selection) {
+				text += strings.TrimSpace(img.AttrOr("alt", ""))
+			})
+		}
+		switch {
+		case text == "":
+			add("empty_link", models.SeverityError, "Link has no text or accessible name", s)
+		case nonDescriptiveLinkTexts[strings.Trim(strings.ToLower(text), ".…!>» ")]:
+			add("non_descriptive_link", models.SeverityWarning,
+				fmt.Sprintf("Link text %q does not describe its target", text), s)
+		}
+	})
+
+	ids := map[string]*goquery.Selection{}
+	reported := map[string]bool{}
+	doc.Find("[id]").Each(func(_ int, s *goquery.Selection) {
+		id := s.AttrOr("id", "")
+		if id == "" {
+			return
+		}
+		if _, seen := ids[id]; !seen {
+			ids[id] = s
+			return
+		}
+		if !reported[id] {
+			reported[id] = true
+			add("duplicate_id", models.SeverityWarning,
+				fmt.Sprintf("id %q is used by more than one element", id), s)
+		}
+	})
+
+	doc.Find("[role]").Each(func(_ int, s *goquery.Selection) {
+		for _, role := range strings.Fields(strings.ToLower(s.AttrOr("role", ""))) {
+			switch {
+			case abstractAriaRoles[role]:
+				add("abstract_role", models.SeverityError,
+					fmt.Sprintf("Abstract ARIA role %q must not be used", role), s)
+			case !ariaRoles[role] && !strings.HasPrefix(role, "doc-") && !strings.HasPrefix(role, "graphics-"):
+				add("invalid_role", models.SeverityError,
+					fmt.Sprintf("%q is not a valid ARIA role", role), s)
+			}
+		}
+	})
+
+	doc.Find(`[aria-hidden="true"]`).Each(func(_ int, s *goquery.Selection) {
+		if isFocusable(s) || s.Find("a[href], button, input, select, textarea").Length() > 0 {
+			add("hidden_focusable", models.SeverityWarning,
+				"Element hidden with aria-hidden contains focusable content", s)
+		}
+	})
+}
+
+func hasAccessibleName(s *goquery.Selection) bool {
+	return strings.TrimSpace(s.AttrOr("aria-label", "")) != "" ||
+		strings.TrimSpace(s.AttrOr("aria-labelledby", "")) != "" ||
+		strings.TrimSpace(s.AttrOr("title", "")) != ""
+}
+
+func isHiddenFromAT(s *goquery.Selection) bool {
+	role := strings.ToLower(s.AttrOr("role", ""))
+	return s.AttrOr("aria-hidden", "") == "true" || role == "presentation" || role == "none"
+}
+
+func isFocusable(s *goquery.Selection) bool {
+	if tabindex, ok := s.Attr("tabindex"); ok {
+		return !strings.HasPrefix(strings.TrimSpace(tabindex), "-")
+	}
+	switch goquery.NodeName(s) {
+	case "a":
+		_, ok := s.Attr("href")
+		return ok
+	case "button", "input", "select", "textarea":
+		_, disabled := s.Attr("disabled")
+		return !disabled
+	}
+	return false
+}
+
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
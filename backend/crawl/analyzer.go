@@ -0,0 +1,133 @@
+package crawl
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/shwetakhatra/url-analyzer/models"
+)
+
+// AnalysisInput is what every analyzer gets to look at
+type AnalysisInput struct {
+	Page *Page
+	// Doc is the parsed document, nil when the page is not HTML
+	Doc *goquery.Document
+	// Body is the page body, transcoded to UTF-8 for HTML
+	Body    []byte
+	URLID   string
+	Options CrawlOptions
+	// Result holds what the analyzers before have produced. The built-in
+	// analyzers fill its typed fields, which back the columns of the URL;
+	// findings and metrics are reported through AnalyzerOutput.
+	Result *CrawlResult
+}
+
+// AnalyzerOutput is what an analyzer found: findings, whose category
+// defaults to the analyzer name, and named metrics stored as they are
+type AnalyzerOutput struct {
+	Findings []models.Finding
+	Metrics  map[string]interface{}
+}
+
+// Analyzer is one step of the analysis pipeline
+type Analyzer interface {
+	// Name identifies the analyzer in settings and stored results
+	Name() string
+	Description() string
+	// HTMLOnly analyzers are skipped for documents that are not HTML
+	HTMLOnly() bool
+	Analyze(in *AnalysisInput) (*AnalyzerOutput, error)
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   []Analyzer
+)
+
+// Register adds an analyzer to the pipeline. Analyzers run in the order
+// they are registered and are enabled unless a setting disables them.
+func Register(a Analyzer) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	for _, existing := range registry {
+		if existing.Name() == a.Name() {
+			panic("analyzer registered twice: " + a.Name())
+		}
+	}
+	registry = append(registry, a)
+}
+
+// Analyzers returns the registered analyzers in pipeline order
+func Analyzers() []Analyzer {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return append([]Analyzer{}, registry...)
+}
+
+// IsAnalyzer tells whether an analyzer of that name is registered
+func IsAnalyzer(name string) bool {
+	for _, a := range Analyzers() {
+		if a.Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// EnabledAnalyzers resolves which analyzers run for a URL: a setting of the
+// URL wins over one of its owner, and analyzers without a setting run
+func EnabledAnalyzers(userSettings, urlSettings map[string]bool) map[string]bool {
+	enabled := map[string]bool{}
+	for _, a := range Analyzers() {
+		on := true
+		if v, ok := userSettings[a.Name()]; ok {
+			on = v
+		}
+		if v, ok := urlSettings[a.Name()]; ok {
+			on = v
+		}
+		enabled[a.Name()] = on
+	}
+	return enabled
+}
+
+// runAnalyzers runs the enabled analyzers in order and records the outcome
+// of each one. A failing analyzer does not stop the others.
+func runAnalyzers(in *AnalysisInput) {
+	result := in.Result
+	for _, a := range Analyzers() {
+		if in.Options.Disabled[a.Name()] || (a.HTMLOnly() && in.Doc == nil) {
+			continue
+		}
+		start := time.Now()
+		out, err := safeAnalyze(a, in)
+		record := models.AnalyzerResult{URLID: in.URLID, Analyzer: a.Name()}
+		if err != nil {
+			record.Error = err.Error()
+		} else if out != nil {
+			for _, finding := range out.Findings {
+				finding.URLID = in.URLID
+				if finding.Category == "" {
+					finding.Category = a.Name()
+				}
+				result.Findings = append(result.Findings, finding)
+			}
+			record.Metrics = out.Metrics
+			record.FindingCount = len(out.Findings)
+		}
+		record.DurationMS = time.Since(start).Milliseconds()
+		result.AnalyzerResults = append(result.AnalyzerResults, record)
+	}
+}
+
+// safeAnalyze turns a panicking analyzer into an error
+func safeAnalyze(a Analyzer, in *AnalysisInput) (out *AnalyzerOutput, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("analyzer %s panicked: %v", a.Name(), r)
+		}
+	}()
+	return a.Analyze(in)
+}
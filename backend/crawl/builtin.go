@@ -0,0 +1,141 @@
+package crawl
+
+import (
+	"net/http"
+
+	"github.com/shwetakhatra/url-analyzer/models"
+)
+
+// builtinAnalyzer adapts the analyzers of this package to the Analyzer
+// interface. They fill the typed fields of the result, which back the
+// columns of the URL, and run returns their metrics; the findings they add
+// are handed back as those of the analyzer.
+type builtinAnalyzer struct {
+	name        string
+	description string
+	htmlOnly    bool
+	run         func(in *AnalysisInput) map[string]interface{}
+}
+
+func (a builtinAnalyzer) Name() string        { return a.name }
+func (a builtinAnalyzer) Description() string { return a.description }
+func (a builtinAnalyzer) HTMLOnly() bool      { return a.htmlOnly }
+
+func (a builtinAnalyzer) Analyze(in *AnalysisInput) (*AnalyzerOutput, error) {
+	before := len(in.Result.Findings)
+	metrics := a.run(in)
+	findings := append([]models.Finding{}, in.Result.Findings[before:]...)
+	in.Result.Findings = in.Result.Findings[:before]
+	return &AnalyzerOutput{Findings: findings, Metrics: metrics}, nil
+}
+
+func init() {
+	for _, a := range []builtinAnalyzer{
+		{"security", "Security response headers and cookie flags", false, func(in *AnalysisInput) map[string]interface{} {
+			cookies := (&http.Response{Header: in.Page.Header}).Cookies()
+			analyzeSecurity(in.Page.Header, cookies, in.Page.URL.Scheme == "https", in.URLID, in.Result)
+			return map[string]interface{}{
+				"score":           in.Result.SecurityScore,
+				"grade":           in.Result.SecurityGrade,
+				"missing_headers": in.Result.MissingSecurityHeaders,
+			}
+		}},
+		{"tls", "TLS version and certificate chain", false, func(in *AnalysisInput) map[string]interface{} {
+			inspectTLS(in.Page.TLS, in.Page.URL.Hostname(), in.URLID, in.Result)
+			if in.Page.TLS == nil {
+				return nil
+			}
+			return map[string]interface{}{
+				"version":    in.Result.TLSVersion,
+				"cert_valid": in.Result.CertValid,
+			}
+		}},
+		{"headings", "Heading outline and structure", true, func(in *AnalysisInput) map[string]interface{} {
+			analyzeHeadings(in.Doc, in.URLID, in.Result)
+			return map[string]interface{}{
+				"headings": len(in.Result.Headings),
+				"h1":       in.Result.H1Count,
+			}
+		}},
+		{"seo", "SEO metadata and audit", true, func(in *AnalysisInput) map[string]interface{} {
+			analyzeSEO(in.Doc, in.Page.Header, in.Page.URL, in.URLID, in.Result)
+			return map[string]interface{}{
+				"score":              in.Result.SEOScore,
+				"title_length":       in.Result.TitleLength,
+				"description_length": in.Result.DescriptionLength,
+			}
+		}},
+		{"accessibility", "Static accessibility audit", true, func(in *AnalysisInput) map[string]interface{} {
+			analyzeAccessibility(in.Doc, in.URLID, in.Result)
+			return nil
+		}},
+		{"structured_data", "JSON-LD, microdata and RDFa items", true, func(in *AnalysisInput) map[string]interface{} {
+			extractStructuredData(in.Doc, in.URLID, in.Result)
+			invalid := 0
+			for _, item := range in.Result.StructuredData {
+				if !item.Valid {
+					invalid++
+				}
+			}
+			return map[string]interface{}{
+				"items":   len(in.Result.StructuredData),
+				"invalid": invalid,
+			}
+		}},
+		{"mixed_content", "Mixed content and subresource integrity", true, func(in *AnalysisInput) map[string]interface{} {
+			analyzeMixedContent(in.Doc, in.Page.URL, in.URLID, in.Result)
+			return nil
+		}},
+		{"forms", "Form inventory and classification", true, func(in *AnalysisInput) map[string]interface{} {
+			analyzeForms(in.Doc, in.Page.URL, in.URLID, in.Result)
+			logins := 0
+			for _, form := range in.Result.Forms {
+				if form.Kind == models.FormLogin {
+					logins++
+				}
+			}
+			return map[string]interface{}{
+				"forms":       len(in.Result.Forms),
+				"login_forms": logins,
+			}
+		}},
+		{"third_parties", "Third-party domains and trackers", true, func(in *AnalysisInput) map[string]interface{} {
+			analyzeThirdParties(in.Doc, in.Page.URL, in.URLID, in.Result)
+			return map[string]interface{}{
+				"third_parties": in.Result.ThirdPartyCount,
+				"trackers":      in.Result.TrackerCount,
+			}
+		}},
+		{"resources", "Subresource inventory and page weight", true, func(in *AnalysisInput) map[string]interface{} {
+			buildInventory(in.Doc, in.Page.URL, len(in.Page.Body), in.Options, in.URLID, in.Result)
+			return map[string]interface{}{
+				"page_weight": in.Result.PageWeight,
+				"requests":    in.Result.RequestCount,
+				"broken":      in.Result.BrokenResourceCount,
+			}
+		}},
+		{"content", "Main text metrics and fingerprint", true, func(in *AnalysisInput) map[string]interface{} {
+			analyzeContent(in.Doc, len(in.Page.Body), in.Result)
+			return map[string]interface{}{
+				"word_count":          in.Result.WordCount,
+				"text_ratio":          in.Result.TextRatio,
+				"sentences":           in.Result.SentenceCount,
+				"avg_sentence_length": in.Result.AvgSentenceLength,
+				"longest_sentence":    in.Result.LongestSentence,
+				"readability":         in.Result.ReadabilityScore,
+				"top_terms":           in.Result.TopTerms,
+			}
+		}},
+		{"links", "Link counts, link checks and fragment validation", true, func(in *AnalysisInput) map[string]interface{} {
+			analyzeLinks(in.Doc, in.Page, in.Options, in.URLID, in.Result)
+			return map[string]interface{}{
+				"internal":         in.Result.InternalLinks,
+				"external":         in.Result.ExternalLinks,
+				"broken":           in.Result.BrokenLinkCount,
+				"broken_fragments": in.Result.BrokenFragmentCount,
+			}
+		}},
+	} {
+		Register(a)
+	}
+}
@@ -0,0 +1,66 @@
+package crawl
+
+import (
+	"bytes"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+)
+
+// metaPrescanSize is how far into the body the HTML spec looks for a
+// <meta> charset declaration
+const metaPrescanSize = 1024
+
+// decodeBody transcodes an HTML body to UTF-8 using the charset announced
+// by a BOM, the Content-Type header or a <meta charset> declaration, and
+// returns the canonical name of the detected encoding.
+func decodeBody(body []byte, contentType string) ([]byte, string, error) {
+	enc, name, certain := charset.DetermineEncoding(body, contentType)
+	// the meta prescan is never reported as certain, so look for it here
+	declared := certain || hasMetaCharset(body)
+	if name == "utf-8" || (!declared && utf8.Valid(body)) {
+		// undeclared pages that already are valid UTF-8 (including plain
+		// ASCII) are left alone instead of being reported as windows-1252
+		return bytes.TrimPrefix(body, []byte("\xef\xbb\xbf")), "utf-8", nil
+	}
+	decoded, err := enc.NewDecoder().Bytes(body)
+	if err != nil {
+		return nil, "", err
+	}
+	// a UTF-16 BOM survives decoding as a UTF-8 one
+	return bytes.TrimPrefix(decoded, []byte("\xef\xbb\xbf")), name, nil
+}
+
+// hasMetaCharset tells whether the start of the body declares its charset
+// in a <meta charset> or <meta http-equiv="Content-Type"> tag
+func hasMetaCharset(body []byte) bool {
+	if len(body) > metaPrescanSize {
+		body = body[:metaPrescanSize]
+	}
+	z := html.NewTokenizer(bytes.NewReader(body))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return false
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			if string(name) != "meta" {
+				continue
+			}
+			for hasAttr {
+				var key, value []byte
+				key, value, hasAttr = z.TagAttr()
+				switch string(key) {
+				case "charset":
+					return true
+				case "content":
+					if strings.Contains(strings.ToLower(string(value)), "charset=") {
+						return true
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package crawl
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDecodeBody(t *testing.T) {
+	padding := "<!--" + strings.Repeat("x", metaPrescanSize) + "-->"
+	tests := []struct {
+		name        string
+		body        string
+		contentType string
+		wantBody    string
+		wantCharset string
+	}{
+		{
+			name:        "utf-8 BOM wins over the header",
+			body:        "\xef\xbb\xbfcafé",
+			contentType: "text/html; charset=iso-8859-1",
+			wantBody:    "café",
+			wantCharset: "utf-8",
+		},
+		{
+			name:        "utf-16 BOM",
+			body:        "\xff\xfec\x00a\x00f\x00\xe9\x00",
+			contentType: "text/html",
+			wantBody:    "café",
+			wantCharset: "utf-16le",
+		},
+		{
+			name:        "header charset",
+			body:        "caf\xe9",
+			contentType: "text/html; charset=ISO-8859-1",
+			wantBody:    "café",
+			wantCharset: "windows-1252",
+		},
+		{
+			name:        "header wins over meta",
+			body:        `<meta charset="iso-8859-1">café`,
+			contentType: "text/html; charset=utf-8",
+			wantBody:    `<meta charset="iso-8859-1">café`,
+			wantCharset: "utf-8",
+		},
+		{
+			name:        "meta charset",
+			body:        `<meta charset="iso-8859-1">caf` + "\xe9",
+			contentType: "text/html",
+			wantBody:    `<meta charset="iso-8859-1">café`,
+			wantCharset: "windows-1252",
+		},
+		{
+			name:        "meta http-equiv",
+			body:        `<meta http-equiv="Content-Type" content="text/html; charset=windows-1252">caf` + "\xe9",
+			contentType: "",
+			wantBody:    `<meta http-equiv="Content-Type" content="text/html; charset=windows-1252">café`,
+			wantCharset: "windows-1252",
+		},
+		{
+			name:        "declared charset applies to ASCII",
+			body:        `<meta charset="iso-8859-1">plain`,
+			contentType: "text/html",
+			wantBody:    `<meta charset="iso-8859-1">plain`,
+			wantCharset: "windows-1252",
+		},
+		{
+			name:        "undeclared valid utf-8",
+			body:        "café",
+			contentType: "text/html",
+			wantBody:    "café",
+			wantCharset: "utf-8",
+		},
+		{
+			name:        "undeclared ASCII",
+			body:        "<p>plain</p>",
+			contentType: "text/html",
+			wantBody:    "<p>plain</p>",
+			wantCharset: "utf-8",
+		},
+		{
+			name:        "undeclared invalid utf-8 falls back to windows-1252",
+			body:        "caf\xe9",
+			contentType: "text/html",
+			wantBody:    "café",
+			wantCharset: "windows-1252",
+		},
+		{
+			name:        "meta beyond the prescan is ignored",
+			body:        padding + `<meta charset="iso-8859-1">café`,
+			contentType: "text/html",
+			wantBody:    padding + `<meta charset="iso-8859-1">café`,
+			wantCharset: "utf-8",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, name, err := decodeBody([]byte(tt.body), tt.contentType)
+			if err != nil {
+				t.Fatalf("decodeBody: %v", err)
+			}
+			if name != tt.wantCharset {
+				t.Errorf("charset = %q, want %q", name, tt.wantCharset)
+			}
+			if string(body) != tt.wantBody {
+				t.Errorf("body = %q, want %q", body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestHasMetaCharset(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want bool
+	}{
+		{"charset attribute", `<head><meta charset="utf-8"></head>`, true},
+		{"self-closing", `<meta charset="utf-8"/>`, true},
+		{"upper case", `<META CHARSET="UTF-8">`, true},
+		{"http-equiv content", `<meta http-equiv="Content-Type" content="text/html; Charset=utf-8">`, true},
+		{"content without charset", `<meta http-equiv="Content-Type" content="text/html">`, false},
+		{"other meta", `<meta name="viewport" content="width=device-width">`, false},
+		{"not a meta tag", `<div charset="utf-8"></div>`, false},
+		{"inside a comment", `<!-- <meta charset="utf-8"> -->`, false},
+		{"no markup", "plain text", false},
+		{"past the prescan", strings.Repeat(" ", metaPrescanSize) + `<meta charset="utf-8">`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hasMetaCharset([]byte(tt.body)); got != tt.want {
+				t.Fatalf("hasMetaCharset(%q) = %v, want %v", tt.body, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package crawl
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/shwetakhatra/url-analyzer/models"
+)
+
+const (
+	pageTimeout = 30 * time.Second
+	linkTimeout = 15 * time.Second
+)
+
+// pageClient fetches the analyzed page itself. It accepts any certificate so
+// that pages with broken TLS can still be analyzed; inspectTLS verifies the
+// chain afterwards and reports what is wrong with it.
+var pageClient = newPageClient()
+
+func newPageClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	return &http.Client{
+		Transport: transport,
+		Timeout:   pageTimeout,
+	}
+}
+
+// securePageClient fetches the page when the request carries credentials,
+// which must not be sent to a server whose certificate does not verify. It
+// fails with an untrustedCertError that still holds the TLS state, so that
+// the certificate can be inspected all the same.
+var securePageClient = newSecurePageClient()
+
+func newSecurePageClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	transport.DialTLSContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		conn, err := dialer.DialContext(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		// the chain is verified below, so that its state survives a failure
+		tlsConn := tls.Client(conn, &tls.Config{InsecureSkipVerify: true, ServerName: host})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		if err := verifyCertificate(tlsConn.ConnectionState(), host); err != nil {
+			tlsConn.Close()
+			return nil, err
+		}
+		return tlsConn, nil
+	}
+	return &http.Client{
+		Transport:     transport,
+		CheckRedirect: dropHeadersAcrossHosts,
+		Timeout:       pageTimeout,
+	}
+}
+
+// dropHeadersAcrossHosts follows redirects like the default policy, but
+// once one leaves the host of the first request the headers, cookies and
+// credentials of the request options stay behind
+func dropHeadersAcrossHosts(req *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+	if strings.EqualFold(req.URL.Hostname(), via[0].URL.Hostname()) {
+		return nil
+	}
+	for name := range via[0].Header {
+		// these are set by the crawler or the client itself
+		if name != "Content-Type" && name != "Referer" {
+			req.Header.Del(name)
+		}
+	}
+	return nil
+}
+
+// untrustedCertError is the refusal to send a request to a server whose
+// certificate does not verify
+type untrustedCertError struct {
+	Host  string
+	State tls.ConnectionState
+	Err   error
+}
+
+func (e *untrustedCertError) Error() string {
+	return "refusing to send credentials to " + e.Host + ": " + e.Err.Error()
+}
+
+func (e *untrustedCertError) Unwrap() error { return e.Err }
+
+// verifyCertificate checks the chain and host name of a server certificate
+// the way the default TLS configuration would
+func verifyCertificate(state tls.ConnectionState, host string) error {
+	if len(state.PeerCertificates) == 0 {
+		return &untrustedCertError{Host: host, State: state, Err: errors.New("server sent no certificate")}
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range state.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := state.PeerCertificates[0].Verify(x509.VerifyOptions{
+		DNSName:       host,
+		Intermediates: intermediates,
+	})
+	if err != nil {
+		return &untrustedCertError{Host: host, State: state, Err: err}
+	}
+	return nil
+}
+
+// linkClient checks links and subresources of the page
+var linkClient = &http.Client{Timeout: linkTimeout}
+
+// newRequest builds a request carrying the headers, cookies and credentials
+// of the request options, if there are any
+func newRequest(method, target string, options *models.RequestOptions) (*http.Request, error) {
+	req, err := http.NewRequest(method, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	if options == nil {
+		return req, nil
+	}
+	for name, value := range options.Headers {
+		req.Header.Set(name, value)
+	}
+	for name, value := range options.Cookies {
+		req.AddCookie(&http.Cookie{Name: name, Value: value})
+	}
+	if options.BasicAuth != nil {
+		req.SetBasicAuth(options.BasicAuth.Username, options.BasicAuth.Password)
+	}
+	return req, nil
+}
+
+// linkOptions returns the request options to send when checking a link:
+// those of the page for links to its own host if they are meant for links
+func linkOptions(opts CrawlOptions, sameHost bool) *models.RequestOptions {
+	if sameHost && opts.Request != nil && opts.Request.ApplyToLinks {
+		return opts.Request
+	}
+	return nil
+}
+
+// withHeaders returns a copy of the request options with further headers
+func withHeaders(options *models.RequestOptions, headers map[string]string) *models.RequestOptions {
+	merged := &models.RequestOptions{}
+	if options != nil {
+		*merged = *options
+	}
+	merged.Headers = map[string]string{}
+	if options != nil {
+		for name, value := range options.Headers {
+			merged.Headers[name] = value
+		}
+	}
+	for name, value := range headers {
+		merged.Headers[name] = value
+	}
+	return merged
+}
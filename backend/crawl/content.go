@@ -0,0 +1,207 @@
+package crawl
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/shwetakhatra/url-analyzer/models"
+	"golang.org/x/net/html"
+)
+
+// topTermCount is how many of the most frequent terms are kept
+const topTermCount = 10
+
+// boilerplate is removed before the main text is extracted
+const boilerplate = `script, style, noscript, template, svg, nav, header, footer, aside, form,
+	[role="navigation"], [role="banner"], [role="contentinfo"], [role="complementary"],
+	[role="search"], [hidden], [aria-hidden="true"]`
+
+// linkListDensity is the share of link text above which a list or table is
+// treated as navigation
+const linkListDensity = 0.7
+
+var sentenceEnd = regexp.MustCompile(`[.!?]+(\s|$)|\n+`)
+
+// stopWords are skipped when counting the most frequent terms
+var stopWords = toSet(strings.Fields(`
+	a about above after again against all also am an and any are aren't as at be because been before being
+	below between both but by can can't cannot could couldn't did didn't do does doesn't doing don't down
+	during each few for from further get got had hadn't has hasn't have haven't having he he'd he'll he's her
+	here here's hers herself him himself his how how's however i i'd i'll i'm i've if in into is isn't it
+	it's its itself just let's like may me might more most much must mustn't my myself need new no nor not
+	now of off on once one only or other ought our ours ourselves out over own same shall shan't she she'd
+	she'll she's should shouldn't so some such than that that's the their theirs them themselves then there
+	there's these they they'd they'll they're they've this those through to too under until up upon us use
+	used using very via was wasn't we we'd we'll we're we've well were weren't what what's when when's where
+	where's which while who who's whom why why's will with within without won't would wouldn't yet you
+	you'd you'll you're you've your yours yourself yourselves`))
+
+// analyzeContent extracts the main text of the page, leaving navigation and
+// other boilerplate out, measures its length, density, sentence structure
+// and readability, and fingerprints it for duplicate detection
+func analyzeContent(doc *goquery.Document, pageSize int, result *CrawlResult) {
+	text := mainText(doc)
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	result.MainText = strings.Join(lines, "\n")
+
+	words := contentWords(text)
+	result.WordCount = len(words)
+	result.ContentHash = contentHash(words)
+	result.SimHash = simHash(words)
+	if pageSize > 0 {
+		result.TextRatio = round1(float64(len(strings.Join(strings.Fields(text), " "))) * 100 / float64(pageSize))
+	}
+	result.TopTerms = topTerms(words)
+	if len(words) == 0 {
+		return
+	}
+
+	sentences := 0
+	for _, sentence := range sentenceEnd.Split(text, -1) {
+		n := len(contentWords(sentence))
+		if n == 0 {
+			continue
+		}
+		sentences++
+		if n > result.LongestSentence {
+			result.LongestSentence = n
+		}
+	}
+	result.SentenceCount = sentences
+	result.AvgSentenceLength = round1(float64(len(words)) / float64(sentences))
+
+	syllables := 0
+	for _, word := range words {
+		syllables += countSyllables(word)
+	}
+	// Flesch reading ease: 60–70 is plain English, below 30 is very difficult
+	result.ReadabilityScore = round1(206.835 - 1.015*float64(len(words))/float64(sentences) -
+		84.6*float64(syllables)/float64(len(words)))
+}
+
+// mainText returns the text of the main content of the page with one line
+// per block element
+func mainText(doc *goquery.Document) string {
+	root := doc.Find(`main, [role="main"]`).First()
+	if root.Length() == 0 {
+		if articles := doc.Find("article"); articles.Length() == 1 {
+			root = articles
+		} else {
+			root = doc.Find("body")
+		}
+	}
+	// work on a copy so that the other analyzers still see the whole page
+	root = root.Clone()
+	root.Find(boilerplate).Remove()
+	root.Find("ul, ol, table, div").Each(func(_ int, s *goquery.Selection) {
+		all := len(strings.TrimSpace(s.Text()))
+		if all > 0 && float64(len(strings.TrimSpace(s.Find("a").Text())))/float64(all) >= linkListDensity {
+			s.Remove()
+		}
+	})
+
+	var sb strings.Builder
+	for _, n := range root.Nodes {
+		writeBlockText(&sb, n)
+	}
+	return sb.String()
+}
+
+// block level elements end a line of text
+var blockElements = toSet([]string{
+	"address", "article", "blockquote", "br", "dd", "div", "dl", "dt", "figcaption", "figure", "h1", "h2",
+	"h3", "h4", "h5", "h6", "hr", "li", "main", "ol", "p", "pre", "section", "table", "td", "th", "tr", "ul",
+})
+
+func writeBlockText(sb *strings.Builder, n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		sb.WriteString(n.Data)
+		return
+	case html.ElementNode, html.DocumentNode:
+	default:
+		return
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		writeBlockText(sb, c)
+	}
+	if blockElements[n.Data] {
+		sb.WriteString("\n")
+	}
+}
+
+// contentWords splits text into lower-cased words, keeping apostrophes
+// inside words
+func contentWords(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r) && r != '\'' && r != '’'
+	})
+	kept := words[:0]
+	for _, word := range words {
+		word = strings.Trim(strings.ReplaceAll(word, "’", "'"), "'")
+		if word != "" {
+			kept = append(kept, word)
+		}
+	}
+	return kept
+}
+
+// topTerms returns the most frequent words that are neither stop words,
+// numbers nor shorter than three letters
+func topTerms(words []string) []models.TermCount {
+	counts := map[string]int{}
+	for _, word := range words {
+		if len([]rune(word)) < 3 || stopWords[word] || strings.IndexFunc(word, unicode.IsLetter) < 0 {
+			continue
+		}
+		counts[word]++
+	}
+	terms := make([]models.TermCount, 0, len(counts))
+	for term, count := range counts {
+		terms = append(terms, models.TermCount{Term: term, Count: count})
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		if terms[i].Count != terms[j].Count {
+			return terms[i].Count > terms[j].Count
+		}
+		return terms[i].Term < terms[j].Term
+	})
+	if len(terms) > topTermCount {
+		terms = terms[:topTermCount]
+	}
+	return terms
+}
+
+// countSyllables estimates the syllables of an English word from its vowel
+// groups
+func countSyllables(word string) int {
+	count := 0
+	prevVowel := false
+	for _, r := range word {
+		vowel := strings.ContainsRune("aeiouy", r)
+		if vowel && !prevVowel {
+			count++
+		}
+		prevVowel = vowel
+	}
+	if strings.HasSuffix(word, "e") && !strings.HasSuffix(word, "le") && count > 1 {
+		count--
+	}
+	if count == 0 {
+		return 1
+	}
+	return count
+}
+
+func round1(v float64) float64 {
+	return math.Round(v*10) / 10
+}
@@ -1,113 +1,302 @@
 package crawl
 
 import (
-	"bufio"
+	"bytes"
+	"crypto/tls"
 	"errors"
+	"io"
 	"net/http"
-	"strings"
+	"net/url"
+	"time"
 
 	"github.com/PuerkitoBio/goquery"
-	"github.com/shwetakhatra/url-analyzer/database"
 	"github.com/shwetakhatra/url-analyzer/models"
 )
 
+// maxBodySize caps how much of a response body is read for analysis
+const maxBodySize = 10 << 20
+
 type CrawlResult struct {
-	HTMLVersion     string
-	Title           string
-	H1Count         int
-	H2Count         int
-	InternalLinks   int
-	ExternalLinks   int
-	BrokenLinkCount int
-	HasLoginForm    bool
+	ContentType         string
+	DocumentKind        string
+	Charset             string
+	HTMLVersion         string
+	DocumentMode        string
+	Title               string
+	H1Count             int
+	H2Count             int
+	H3Count             int
+	H4Count             int
+	H5Count             int
+	H6Count             int
+	InternalLinks       int
+	ExternalLinks       int
+	BrokenLinkCount     int
+	BrokenFragmentCount int
+	HasLoginForm        bool
+
+	MetaDescription   string
+	CanonicalURL      string
+	RobotsMeta        string
+	XRobotsTag        string
+	Viewport          string
+	Lang              string
+	TitleLength       int
+	DescriptionLength int
+	OpenGraph         map[string]string
+	TwitterCard       map[string]string
+	SEOScore          int
+
+	SecurityHeaders        map[string]string
+	MissingSecurityHeaders []string
+	SecurityScore          int
+	SecurityGrade          string
+
+	TLSVersion        string
+	CertNotAfter      *time.Time
+	CertHostnameMatch bool
+	CertValid         bool
+
+	WordCount         int
+	TextRatio         float64
+	SentenceCount     int
+	AvgSentenceLength float64
+	LongestSentence   int
+	ReadabilityScore  float64
+	TopTerms          []models.TermCount
+	MainText          string
+	ContentHash       string
+	SimHash           uint64
+
+	PageWeight          int64
+	RequestCount        int
+	BrokenResourceCount int
+	ThirdPartyCount     int
+	TrackerCount        int
+
+	Headings        []models.Heading
+	Findings        []models.Finding
+	StructuredData  []models.StructuredData
+	Certificates    []models.Certificate
+	Resources       []models.Resource
+	Forms           []models.Form
+	ThirdParties    []models.ThirdParty
+	AnalyzerResults []models.AnalyzerResult
+	RuleResults     []models.RuleResult
+	ExtractedValues []models.ExtractedValue
+	ProfileResults  []models.ProfileResult
+	BrokenLinks     []models.BrokenLink
+	Links           []string
+
+	// Page is the fetched page the result was computed from
+	Page *Page
 }
 
-func CrawlURL(rawURL string, urlID string) (*CrawlResult, error) {
-	resp, err := http.Get(rawURL)
+// CrawlOptions tunes what CrawlURL does beyond the default analysis
+type CrawlOptions struct {
+	// CheckResources requests every subresource to measure the page weight
+	CheckResources bool
+	// History makes the analysis offline: link, fragment and resource
+	// checks take their results from it instead of the network
+	History *CheckHistory
+	// Disabled names the analyzers to skip
+	Disabled map[string]bool
+	// Rules are the assertion rules that apply to the URL
+	Rules []models.Rule
+	// Extractors are the field extractors that apply to the URL
+	Extractors []models.Extractor
+	// Request holds the headers, cookies and credentials sent for the page
+	Request *models.RequestOptions
+	// Login is carried out before the page is fetched with its session
+	Login *models.LoginRecipe
+	// ETag and LastModified of the last crawl make the page request
+	// conditional
+	ETag         string
+	LastModified string
+}
+
+// ErrNotModified is returned by CrawlURL when the server answers the
+// conditional request for a page with 304 Not Modified
+var ErrNotModified = errors.New("page not modified since the last crawl")
+
+// Page is a fetched response, either fresh from the network or restored
+// from a snapshot
+type Page struct {
+	// URL is the final URL after redirects
+	URL        *url.URL
+	StatusCode int
+	Header     http.Header
+	// TLS is nil for plain HTTP and for pages restored from a snapshot
+	TLS  *tls.ConnectionState
+	Body []byte
+	// Redirects are the responses that led to URL, oldest first
+	Redirects []models.Redirect
+	FetchedAt time.Time
+}
+
+// CrawlURL fetches a page and analyzes it. The fetched page is kept in the
+// result so that it can be archived. When the credentials of the URL are
+// not sent because the certificate of the server does not verify, the error
+// comes with a result that holds the inspection of that certificate.
+func CrawlURL(rawURL string, urlID string, opts CrawlOptions) (*CrawlResult, error) {
+	client, err := pageSession(opts, opts.Request)
+	if err != nil {
+		return refusedResult(err, urlID), err
+	}
+	options := opts.Request
+	if opts.ETag != "" || opts.LastModified != "" {
+		conditional := map[string]string{}
+		if opts.ETag != "" {
+			conditional["If-None-Match"] = opts.ETag
+		}
+		if opts.LastModified != "" {
+			conditional["If-Modified-Since"] = opts.LastModified
+		}
+		options = withHeaders(opts.Request, conditional)
+	}
+	page, err := fetchPage(client, rawURL, options)
+	if err != nil {
+		return refusedResult(err, urlID), err
+	}
+	if page.StatusCode == http.StatusNotModified {
+		return nil, ErrNotModified
+	}
+	if opts.Login != nil && backOnLoginPage(page, opts.Login, rawURL) {
+		return nil, errors.New("login failed: the page redirected to the login page")
+	}
+	result, err := AnalyzePage(page, urlID, opts)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
+	result.Page = page
+	return result, nil
+}
+
+// refusedResult inspects the certificate a request was refused over, or
+// returns nil if the error is not such a refusal
+func refusedResult(err error, urlID string) *CrawlResult {
+	var untrusted *untrustedCertError
+	if !errors.As(err, &untrusted) {
+		return nil
+	}
+	result := &CrawlResult{}
+	inspectTLS(&untrusted.State, untrusted.Host, urlID, result)
+	return result
+}
+
+// FetchPage downloads a page with the given request options, failing on
+// error statuses
+func FetchPage(rawURL string, options *models.RequestOptions) (*Page, error) {
+	client, err := pageSession(CrawlOptions{Request: options}, options)
+	if err != nil {
+		return nil, err
+	}
+	return fetchPage(client, rawURL, options)
+}
 
-	if resp.StatusCode >= 400 {
+func fetchPage(client *http.Client, rawURL string, options *models.RequestOptions) (*Page, error) {
+	page, err := fetchResponse(client, rawURL, options)
+	if err != nil {
+		return nil, err
+	}
+	if page.StatusCode >= 400 {
 		return nil, errors.New("bad response from server")
 	}
+	return page, nil
+}
 
-	buffered := bufio.NewReader(resp.Body)
-	peeked, err := buffered.Peek(4096)
+// fetchResponse downloads a page whatever its status
+func fetchResponse(client *http.Client, rawURL string, options *models.RequestOptions) (*Page, error) {
+	req, err := newRequest(http.MethodGet, rawURL, options)
 	if err != nil {
 		return nil, err
 	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
 
-	htmlVersion := detectHTMLVersion(string(peeked))
-	doc, err := goquery.NewDocumentFromReader(buffered)
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
 	if err != nil {
 		return nil, err
 	}
+	// every request after a redirect knows the response that caused it
+	var redirects []models.Redirect
+	for r := resp.Request; r.Response != nil; r = r.Response.Request {
+		redirects = append([]models.Redirect{{
+			URL:        r.Response.Request.URL.String(),
+			StatusCode: r.Response.StatusCode,
+		}}, redirects...)
+	}
+	return &Page{
+		URL:        resp.Request.URL,
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		TLS:        resp.TLS,
+		Body:       body,
+		Redirects:  redirects,
+		FetchedAt:  time.Now(),
+	}, nil
+}
 
+// AnalyzePage detects the type and encoding of a fetched page, parses it
+// and runs the enabled analyzers over it
+func AnalyzePage(page *Page, urlID string, opts CrawlOptions) (*CrawlResult, error) {
+	body := page.Body
+	contentType := detectContentType(page.Header.Get("Content-Type"), body)
 	result := &CrawlResult{
-		HTMLVersion: htmlVersion,
-		Title:       doc.Find("title").Text(),
-		H1Count:     doc.Find("h1").Length(),
-		H2Count:     doc.Find("h2").Length(),
-		HasLoginForm: doc.Find(`input[type="password"]`).Length() > 0,
-	}
-
-	internal, external, broken := 0, 0, 0
-	base := resp.Request.URL.Hostname()
-
-	doc.Find("a[href]").Each(func(i int, s *goquery.Selection) {
-		href, _ := s.Attr("href")
-		if strings.HasPrefix(href, "http") {
-			if strings.Contains(href, base) {
-				internal++
-			} else {
-				external++
-			}
-			if status, ok := getLinkStatus(href); !ok {
-				broken++
-				brokenLink := models.BrokenLink{
-					URLID:  urlID,
-					Link:   href,
-					Status: status,
-				}
-				database.DB.Create(&brokenLink)
-			}
-		}
-	})
+		ContentType:  contentType,
+		DocumentKind: documentKind(contentType, body),
+	}
+	in := &AnalysisInput{Page: page, Body: body, URLID: urlID, Options: opts, Result: result}
+	if !isHTMLKind(result.DocumentKind) {
+		// only the analyzers of the response itself apply to JSON, PDF,
+		// images and the like
+		runAnalyzers(in)
+		return result, nil
+	}
+
+	body, charset, err := decodeBody(body, page.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, err
+	}
+	result.Charset = charset
+
+	dt := parseDoctype(body)
+	result.HTMLVersion = dt.Version()
+	result.DocumentMode = dt.Mode()
 
-	result.InternalLinks = internal
-	result.ExternalLinks = external
-	result.BrokenLinkCount = broken
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
 
+	result.Title = doc.Find("title").Text()
+	result.HasLoginForm = doc.Find(`input[type="password"]`).Length() > 0
+	in.Doc = doc
+	in.Body = body
+	runAnalyzers(in)
 	return result, nil
 }
 
-func getLinkStatus(link string) (int, bool) {
-	resp, err := http.Head(link)
+// checkLink requests a link, or looks it up in the history of an offline analysis
+func checkLink(link string, sameHost bool, opts CrawlOptions) (int, bool) {
+	if opts.History != nil {
+		return opts.History.linkStatus(link)
+	}
+	return getLinkStatus(link, linkOptions(opts, sameHost))
+}
+
+func getLinkStatus(link string, options *models.RequestOptions) (int, bool) {
+	req, err := newRequest(http.MethodHead, link, options)
+	if err != nil {
+		return 0, false
+	}
+	resp, err := linkClient.Do(req)
 	if err != nil {
 		return 0, false
 	}
 	defer resp.Body.Close()
 	return resp.StatusCode, resp.StatusCode < 400
 }
-
-
-func detectHTMLVersion(content string) string {
-	content = strings.ToLower(content)
-	doctypes := map[string]string{
-		"<!doctype html>":                                                        "HTML5",
-		"<!doctype html public \"-//w3c//dtd html 4.01 transitional//en\"":      "HTML 4.01 Transitional",
-		"<!doctype html public \"-//w3c//dtd html 4.01 strict//en\"":            "HTML 4.01 Strict",
-		"<!doctype html public \"-//w3c//dtd html 4.01 frameset//en\"":          "HTML 4.01 Frameset",
-		"<!doctype html public \"-//w3c//dtd xhtml 1.0 transitional//en\"":      "XHTML 1.0 Transitional",
-		"<!doctype html public \"-//w3c//dtd xhtml 1.0 strict//en\"":            "XHTML 1.0 Strict",
-		"<!doctype html public \"-//w3c//dtd xhtml 1.0 frameset//en\"":          "XHTML 1.0 Frameset",
-	}
-	for prefix, version := range doctypes {
-		if strings.HasPrefix(content, prefix) {
-			return version
-		}
-	}
-	return "Unknown"
-}
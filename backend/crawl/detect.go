@@ -0,0 +1,74 @@
+package crawl
+
+import (
+	"bytes"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// Document kinds reported for a fetched response.
+const (
+	KindHTML   = "html"
+	KindXHTML  = "xhtml"
+	KindXML    = "xml"
+	KindJSON   = "json"
+	KindPDF    = "pdf"
+	KindImage  = "image"
+	KindText   = "text"
+	KindEmpty  = "empty"
+	KindBinary = "binary"
+)
+
+// sniffLen is the number of bytes inspected by content sniffing
+const sniffLen = 512
+
+// detectContentType resolves the media type of a response from its
+// Content-Type header, falling back to content sniffing when the header
+// is missing or too generic to be useful.
+func detectContentType(header string, body []byte) string {
+	if mediaType, _, err := mime.ParseMediaType(header); err == nil {
+		mediaType = strings.ToLower(mediaType)
+		if mediaType != "application/octet-stream" && mediaType != "application/unknown" {
+			return mediaType
+		}
+	}
+	sniffed, _, _ := mime.ParseMediaType(http.DetectContentType(body))
+	if sniffed == "text/plain" && looksLikeJSON(body) {
+		return "application/json"
+	}
+	return sniffed
+}
+
+// documentKind maps a media type to one of the Kind constants
+func documentKind(mediaType string, body []byte) string {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return KindEmpty
+	}
+	switch {
+	case mediaType == "text/html":
+		return KindHTML
+	case mediaType == "application/xhtml+xml":
+		return KindXHTML
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		return KindJSON
+	case mediaType == "application/pdf":
+		return KindPDF
+	case strings.HasPrefix(mediaType, "image/"):
+		return KindImage
+	case mediaType == "text/xml" || mediaType == "application/xml" || strings.HasSuffix(mediaType, "+xml"):
+		return KindXML
+	case strings.HasPrefix(mediaType, "text/"):
+		return KindText
+	}
+	return KindBinary
+}
+
+func isHTMLKind(kind string) bool {
+	return kind == KindHTML || kind == KindXHTML
+}
+
+func looksLikeJSON(body []byte) bool {
+	trimmed := bytes.TrimSpace(bytes.TrimPrefix(body, []byte("\xef\xbb\xbf")))
+	return len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[')
+}
@@ -0,0 +1,363 @@
+package crawl
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/shwetakhatra/url-analyzer/models"
+)
+
+// diffContext is how many unchanged lines surround a change in a text diff
+const diffContext = 2
+
+// maxDiffCells bounds the work of the line diff; when the changed parts of
+// two texts are larger, they are shown as one replaced block
+const maxDiffCells = 4_000_000
+
+// FieldChange is a page level value that differs between two runs
+type FieldChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
+
+// SetChange lists the entries one run has and the other has not
+type SetChange struct {
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+}
+
+type BrokenLinkChange struct {
+	NewlyBroken []string `json:"newly_broken"`
+	Fixed       []string `json:"fixed"`
+	Delta       int      `json:"delta"`
+}
+
+type FindingChange struct {
+	Added    []models.RunFinding `json:"added"`
+	Resolved []models.RunFinding `json:"resolved"`
+}
+
+// RuleChange lists the rules that started or stopped failing
+type RuleChange struct {
+	Failed []models.RunRule `json:"failed"`
+	Fixed  []models.RunRule `json:"fixed"`
+}
+
+// DiffHunk is a block of the main text diff in unified diff style: every
+// line starts with ' ', '-' or '+'
+type DiffHunk struct {
+	OldStart int      `json:"old_start"`
+	OldLines int      `json:"old_lines"`
+	NewStart int      `json:"new_start"`
+	NewLines int      `json:"new_lines"`
+	Lines    []string `json:"lines"`
+}
+
+// RunDiff is the structured difference between two runs of a URL
+type RunDiff struct {
+	From           int              `json:"from"`
+	To             int              `json:"to"`
+	Fields         []FieldChange    `json:"fields"`
+	Headings       SetChange        `json:"headings"`
+	Links          SetChange        `json:"links"`
+	BrokenLinks    BrokenLinkChange `json:"broken_links"`
+	Findings       FindingChange    `json:"findings"`
+	Rules          RuleChange       `json:"rules"`
+	ContentChanged bool             `json:"content_changed"`
+	Text           []DiffHunk       `json:"text"`
+}
+
+// DiffRuns compares an older run with a newer one
+func DiffRuns(from, to models.Run) RunDiff {
+	a, b := from.Summary, to.Summary
+	diff := RunDiff{
+		From:           from.Version,
+		To:             to.Version,
+		Fields:         []FieldChange{},
+		Headings:       setDiff(a.Headings, b.Headings),
+		Links:          setDiff(a.Links, b.Links),
+		ContentChanged: a.ContentHash != b.ContentHash,
+		Text:           diffLines(splitLines(from.MainText), splitLines(to.MainText)),
+	}
+
+	compare := func(field string, before, after interface{}) {
+		if before != after {
+			diff.Fields = append(diff.Fields, FieldChange{Field: field, Old: before, New: after})
+		}
+	}
+	compare("status_code", a.StatusCode, b.StatusCode)
+	compare("document_kind", a.DocumentKind, b.DocumentKind)
+	compare("title", a.Title, b.Title)
+	compare("meta_description", a.MetaDescription, b.MetaDescription)
+	compare("canonical_url", a.CanonicalURL, b.CanonicalURL)
+	compare("robots_meta", a.RobotsMeta, b.RobotsMeta)
+	compare("lang", a.Lang, b.Lang)
+	compare("html_version", a.HTMLVersion, b.HTMLVersion)
+	compare("word_count", a.WordCount, b.WordCount)
+	compare("internal_links", a.InternalLinks, b.InternalLinks)
+	compare("external_links", a.ExternalLinks, b.ExternalLinks)
+	compare("seo_score", a.SEOScore, b.SEOScore)
+	compare("security_score", a.SecurityScore, b.SecurityScore)
+	compare("security_grade", a.SecurityGrade, b.SecurityGrade)
+	compare("page_weight", a.PageWeight, b.PageWeight)
+	compare("third_party_count", a.ThirdPartyCount, b.ThirdPartyCount)
+	compare("tracker_count", a.TrackerCount, b.TrackerCount)
+
+	// extracted fields show up as fields named after their extractor
+	var names []string
+	for name := range a.Extracted {
+		names = append(names, name)
+	}
+	for name := range b.Extracted {
+		if _, ok := a.Extracted[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		before, after := a.Extracted[name], b.Extracted[name]
+		if strings.Join(before, "\x00") != strings.Join(after, "\x00") || len(before) != len(after) {
+			diff.Fields = append(diff.Fields, FieldChange{Field: "extracted." + name, Old: before, New: after})
+		}
+	}
+
+	broken := setDiff(a.BrokenLinkURLs, b.BrokenLinkURLs)
+	diff.BrokenLinks = BrokenLinkChange{
+		NewlyBroken: broken.Added,
+		Fixed:       broken.Removed,
+		Delta:       b.BrokenLinks - a.BrokenLinks,
+	}
+
+	findingKey := func(f models.RunFinding) string {
+		return f.Category + "\x00" + f.Code + "\x00" + f.Selector + "\x00" + f.Message
+	}
+	oldFindings := map[string]bool{}
+	for _, f := range a.Findings {
+		oldFindings[findingKey(f)] = true
+	}
+	newFindings := map[string]bool{}
+	diff.Findings = FindingChange{Added: []models.RunFinding{}, Resolved: []models.RunFinding{}}
+	for _, f := range b.Findings {
+		newFindings[findingKey(f)] = true
+		if !oldFindings[findingKey(f)] {
+			diff.Findings.Added = append(diff.Findings.Added, f)
+		}
+	}
+	for _, f := range a.Findings {
+		if !newFindings[findingKey(f)] {
+			diff.Findings.Resolved = append(diff.Findings.Resolved, f)
+		}
+	}
+
+	// a rule missing from a run counts as passed there, it was not attached
+	passedBefore := map[string]bool{}
+	for _, r := range a.Rules {
+		passedBefore[r.RuleID] = r.Passed
+	}
+	diff.Rules = RuleChange{Failed: []models.RunRule{}, Fixed: []models.RunRule{}}
+	failedAfter := map[string]bool{}
+	for _, r := range b.Rules {
+		if r.Passed {
+			continue
+		}
+		failedAfter[r.RuleID] = true
+		if passed, ok := passedBefore[r.RuleID]; !ok || passed {
+			diff.Rules.Failed = append(diff.Rules.Failed, r)
+		}
+	}
+	for _, r := range a.Rules {
+		if !r.Passed && !failedAfter[r.RuleID] {
+			diff.Rules.Fixed = append(diff.Rules.Fixed, r)
+		}
+	}
+	return diff
+}
+
+// Summary condenses the diff into the change summary stored with a run
+func (d RunDiff) Summary() *models.ChangeSummary {
+	summary := &models.ChangeSummary{
+		ComparedTo:       d.From,
+		Fields:           []string{},
+		HeadingsAdded:    len(d.Headings.Added),
+		HeadingsRemoved:  len(d.Headings.Removed),
+		LinksAdded:       len(d.Links.Added),
+		LinksRemoved:     len(d.Links.Removed),
+		BrokenLinksAdded: len(d.BrokenLinks.NewlyBroken),
+		BrokenLinksFixed: len(d.BrokenLinks.Fixed),
+		FindingsAdded:    len(d.Findings.Added),
+		FindingsResolved: len(d.Findings.Resolved),
+		RulesFailed:      len(d.Rules.Failed),
+		RulesFixed:       len(d.Rules.Fixed),
+		ContentChanged:   d.ContentChanged,
+	}
+	for _, field := range d.Fields {
+		summary.Fields = append(summary.Fields, field.Field)
+	}
+	for _, hunk := range d.Text {
+		for _, line := range hunk.Lines {
+			switch line[0] {
+			case '+':
+				summary.LinesAdded++
+			case '-':
+				summary.LinesRemoved++
+			}
+		}
+	}
+	return summary
+}
+
+// setDiff compares two lists as multisets, keeping the order of appearance
+func setDiff(before, after []string) SetChange {
+	change := SetChange{Added: []string{}, Removed: []string{}}
+	counts := map[string]int{}
+	for _, v := range before {
+		counts[v]++
+	}
+	for _, v := range after {
+		if counts[v] > 0 {
+			counts[v]--
+		} else {
+			change.Added = append(change.Added, v)
+		}
+	}
+	for _, v := range before {
+		if counts[v] > 0 {
+			counts[v]--
+			change.Removed = append(change.Removed, v)
+		}
+	}
+	return change
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
+
+type lineOp struct {
+	kind byte
+	text string
+}
+
+// diffLines computes a line diff of two texts and groups it into hunks
+func diffLines(a, b []string) []DiffHunk {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var ops []lineOp
+	for _, line := range a[:prefix] {
+		ops = append(ops, lineOp{' ', line})
+	}
+	ops = append(ops, diffMiddle(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, lineOp{' ', line})
+	}
+	return hunks(ops)
+}
+
+// diffMiddle diffs the part of two texts between their common prefix and
+// suffix through their longest common subsequence
+func diffMiddle(a, b []string) []lineOp {
+	var ops []lineOp
+	if len(a)*len(b) > maxDiffCells {
+		for _, line := range a {
+			ops = append(ops, lineOp{'-', line})
+		}
+		for _, line := range b {
+			ops = append(ops, lineOp{'+', line})
+		}
+		return ops
+	}
+
+	// lcs[i*(m+1)+j] is the LCS length of a[i:] and b[j:]
+	n, m := len(a), len(b)
+	lcs := make([]int32, (n+1)*(m+1))
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i*(m+1)+j] = lcs[(i+1)*(m+1)+j+1] + 1
+			} else {
+				lcs[i*(m+1)+j] = max(lcs[(i+1)*(m+1)+j], lcs[i*(m+1)+j+1])
+			}
+		}
+	}
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, lineOp{' ', a[i]})
+			i++
+			j++
+		case lcs[(i+1)*(m+1)+j] >= lcs[i*(m+1)+j+1]:
+			ops = append(ops, lineOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, lineOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		ops = append(ops, lineOp{'-', a[i]})
+	}
+	for ; j < m; j++ {
+		ops = append(ops, lineOp{'+', b[j]})
+	}
+	return ops
+}
+
+// hunks groups changed lines with diffContext lines around them, merging
+// changes that are close to each other
+func hunks(ops []lineOp) []DiffHunk {
+	oldPos := make([]int, len(ops))
+	newPos := make([]int, len(ops))
+	o, n := 0, 0
+	for i, op := range ops {
+		oldPos[i], newPos[i] = o, n
+		if op.kind != '+' {
+			o++
+		}
+		if op.kind != '-' {
+			n++
+		}
+	}
+
+	out := []DiffHunk{}
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+		end := i
+		for j := i; j < len(ops); j++ {
+			if ops[j].kind != ' ' {
+				end = j
+			} else if j-end > 2*diffContext {
+				break
+			}
+		}
+		start := max(i-diffContext, 0)
+		stop := min(end+diffContext+1, len(ops))
+		hunk := DiffHunk{OldStart: oldPos[start] + 1, NewStart: newPos[start] + 1}
+		for _, op := range ops[start:stop] {
+			hunk.Lines = append(hunk.Lines, string(op.kind)+op.text)
+			if op.kind != '+' {
+				hunk.OldLines++
+			}
+			if op.kind != '-' {
+				hunk.NewLines++
+			}
+		}
+		out = append(out, hunk)
+		i = stop
+	}
+	return out
+}
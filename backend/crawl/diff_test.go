@@ -0,0 +1,159 @@
+package crawl
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func numberedLines(n int) []string {
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = strconv.Itoa(i + 1)
+	}
+	return lines
+}
+
+func replaced(lines []string, changes map[int]string) []string {
+	out := append([]string(nil), lines...)
+	for i, line := range changes {
+		out[i] = line
+	}
+	return out
+}
+
+func renderOps(ops []lineOp) []string {
+	out := make([]string, len(ops))
+	for i, op := range ops {
+		out[i] = string(op.kind) + op.text
+	}
+	return out
+}
+
+func TestDiffMiddle(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []string
+		want []string
+	}{
+		{"both empty", nil, nil, []string{}},
+		{"only added", nil, []string{"x", "y"}, []string{"+x", "+y"}},
+		{"only removed", []string{"x", "y"}, nil, []string{"-x", "-y"}},
+		{"replaced line removes first", []string{"a"}, []string{"b"}, []string{"-a", "+b"}},
+		{"keeps the common subsequence", []string{"a", "b", "c"}, []string{"b", "c", "e"}, []string{"-a", " b", " c", "+e"}},
+		{"interleaved", []string{"a", "x", "b", "y", "c"}, []string{"a", "b", "z", "c"}, []string{" a", "-x", " b", "-y", "+z", " c"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := renderOps(diffMiddle(tt.a, tt.b)); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("diffMiddle(%q, %q) = %q, want %q", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffMiddleTooLarge(t *testing.T) {
+	// beyond maxDiffCells the texts are replaced as a whole, even where
+	// they share lines
+	a := numberedLines(2001)
+	b := append([]string{"new"}, a...)
+	ops := diffMiddle(a, b)
+	if len(ops) != len(a)+len(b) {
+		t.Fatalf("got %d ops, want %d", len(ops), len(a)+len(b))
+	}
+	for i, op := range ops {
+		want := byte('-')
+		if i >= len(a) {
+			want = '+'
+		}
+		if op.kind != want {
+			t.Fatalf("op %d is %q, want %q", i, op.kind, want)
+		}
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []string
+		want []DiffHunk
+	}{
+		{
+			name: "identical",
+			a:    numberedLines(5),
+			b:    numberedLines(5),
+			want: []DiffHunk{},
+		},
+		{
+			name: "both empty",
+			want: []DiffHunk{},
+		},
+		{
+			name: "added to an empty text",
+			b:    []string{"x", "y"},
+			want: []DiffHunk{{OldStart: 1, OldLines: 0, NewStart: 1, NewLines: 2, Lines: []string{"+x", "+y"}}},
+		},
+		{
+			name: "one line replaced with context",
+			a:    numberedLines(7),
+			b:    replaced(numberedLines(7), map[int]string{3: "X"}),
+			want: []DiffHunk{{
+				OldStart: 2, OldLines: 5, NewStart: 2, NewLines: 5,
+				Lines: []string{" 2", " 3", "-4", "+X", " 5", " 6"},
+			}},
+		},
+		{
+			name: "line appended",
+			a:    numberedLines(4),
+			b:    append(numberedLines(4), "5"),
+			want: []DiffHunk{{
+				OldStart: 3, OldLines: 2, NewStart: 3, NewLines: 3,
+				Lines: []string{" 3", " 4", "+5"},
+			}},
+		},
+		{
+			name: "close changes merge into one hunk",
+			a:    numberedLines(8),
+			b:    replaced(numberedLines(8), map[int]string{1: "B", 5: "F"}),
+			want: []DiffHunk{{
+				OldStart: 1, OldLines: 8, NewStart: 1, NewLines: 8,
+				Lines: []string{" 1", "-2", "+B", " 3", " 4", " 5", "-6", "+F", " 7", " 8"},
+			}},
+		},
+		{
+			name: "distant changes make two hunks",
+			a:    numberedLines(12),
+			b:    replaced(numberedLines(12), map[int]string{1: "B", 9: "J"}),
+			want: []DiffHunk{
+				{OldStart: 1, OldLines: 4, NewStart: 1, NewLines: 4, Lines: []string{" 1", "-2", "+B", " 3", " 4"}},
+				{OldStart: 8, OldLines: 5, NewStart: 8, NewLines: 5, Lines: []string{" 8", " 9", "-10", "+J", " 11", " 12"}},
+			},
+		},
+		{
+			name: "line numbers follow insertions",
+			a:    numberedLines(12),
+			b:    append([]string{"a", "b", "c"}, replaced(numberedLines(12), map[int]string{9: "J"})...),
+			want: []DiffHunk{
+				{OldStart: 1, OldLines: 2, NewStart: 1, NewLines: 5, Lines: []string{"+a", "+b", "+c", " 1", " 2"}},
+				{OldStart: 8, OldLines: 5, NewStart: 11, NewLines: 5, Lines: []string{" 8", " 9", "-10", "+J", " 11", " 12"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := diffLines(tt.a, tt.b)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("diffLines:\n got %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffLinesSplitsText(t *testing.T) {
+	got := diffLines(splitLines("a\nb\nc"), splitLines("a\nc"))
+	want := []string{" a", "-b", " c"}
+	if len(got) != 1 || !reflect.DeepEqual(got[0].Lines, want) {
+		t.Fatalf("diffLines = %+v, want one hunk with %s", got, strings.Join(want, ","))
+	}
+}
@@ -0,0 +1,254 @@
+package crawl
+
+import (
+	"bytes"
+	"strings"
+)
+
+// Document modes as defined by the HTML parsing spec.
+const (
+	ModeNoQuirks      = "no-quirks"
+	ModeLimitedQuirks = "limited-quirks"
+	ModeQuirks        = "quirks"
+)
+
+type doctype struct {
+	Present  bool
+	Name     string
+	PublicID string
+	SystemID string
+}
+
+// public identifiers mapped to a readable version, keyed in lower case
+var publicIDVersions = map[string]string{
+	"-//ietf//dtd html//en":                     "HTML 2.0",
+	"-//ietf//dtd html 2.0//en":                 "HTML 2.0",
+	"-//w3c//dtd html 3.2//en":                  "HTML 3.2",
+	"-//w3c//dtd html 3.2 final//en":            "HTML 3.2",
+	"-//w3c//dtd html 3.2 draft//en":            "HTML 3.2",
+	"-//w3c//dtd html 4.0//en":                  "HTML 4.0 Strict",
+	"-//w3c//dtd html 4.0 transitional//en":     "HTML 4.0 Transitional",
+	"-//w3c//dtd html 4.0 frameset//en":         "HTML 4.0 Frameset",
+	"-//w3c//dtd html 4.01//en":                 "HTML 4.01 Strict",
+	"-//w3c//dtd html 4.01 strict//en":          "HTML 4.01 Strict",
+	"-//w3c//dtd html 4.01 transitional//en":    "HTML 4.01 Transitional",
+	"-//w3c//dtd html 4.01 frameset//en":        "HTML 4.01 Frameset",
+	"-//w3c//dtd xhtml 1.0 strict//en":          "XHTML 1.0 Strict",
+	"-//w3c//dtd xhtml 1.0 transitional//en":    "XHTML 1.0 Transitional",
+	"-//w3c//dtd xhtml 1.0 frameset//en":        "XHTML 1.0 Frameset",
+	"-//w3c//dtd xhtml 1.1//en":                 "XHTML 1.1",
+	"-//w3c//dtd xhtml basic 1.0//en":           "XHTML Basic 1.0",
+	"-//w3c//dtd xhtml basic 1.1//en":           "XHTML Basic 1.1",
+	"-//wapforum//dtd xhtml mobile 1.0//en":     "XHTML Mobile 1.0",
+	"-//wapforum//dtd xhtml mobile 1.1//en":     "XHTML Mobile 1.1",
+	"-//wapforum//dtd xhtml mobile 1.2//en":     "XHTML Mobile 1.2",
+	"-//w3c//dtd xhtml 1.1 plus mathml 2.0//en": "XHTML 1.1 plus MathML 2.0",
+}
+
+// public identifier prefixes that force quirks mode
+var quirksPublicPrefixes = []string{
+	"+//silmaril//dtd html pro v0r11 19970101//",
+	"-//as//dtd html 3.0 aswedit + extensions//",
+	"-//advasoft ltd//dtd html 3.0 aswedit + extensions//",
+	"-//ietf//dtd html 2.0 level 1//",
+	"-//ietf//dtd html 2.0 level 2//",
+	"-//ietf//dtd html 2.0 strict level 1//",
+	"-//ietf//dtd html 2.0 strict level 2//",
+	"-//ietf//dtd html 2.0 strict//",
+	"-//ietf//dtd html 2.0//",
+	"-//ietf//dtd html 2.1e//",
+	"-//ietf//dtd html 3.0//",
+	"-//ietf//dtd html 3.2 final//",
+	"-//ietf//dtd html 3.2//",
+	"-//ietf//dtd html 3//",
+	"-//ietf//dtd html level 0//",
+	"-//ietf//dtd html level 1//",
+	"-//ietf//dtd html level 2//",
+	"-//ietf//dtd html level 3//",
+	"-//ietf//dtd html strict level 0//",
+	"-//ietf//dtd html strict level 1//",
+	"-//ietf//dtd html strict level 2//",
+	"-//ietf//dtd html strict level 3//",
+	"-//ietf//dtd html strict//",
+	"-//ietf//dtd html//",
+	"-//metrius//dtd metrius presentational//",
+	"-//microsoft//dtd internet explorer 2.0 html strict//",
+	"-//microsoft//dtd internet explorer 2.0 html//",
+	"-//microsoft//dtd internet explorer 2.0 tables//",
+	"-//microsoft//dtd internet explorer 3.0 html strict//",
+	"-//microsoft//dtd internet explorer 3.0 html//",
+	"-//microsoft//dtd internet explorer 3.0 tables//",
+	"-//netscape comm. corp.//dtd html//",
+	"-//netscape comm. corp.//dtd strict html//",
+	"-//o'reilly and associates//dtd html 2.0//",
+	"-//o'reilly and associates//dtd html extended 1.0//",
+	"-//o'reilly and associates//dtd html extended relaxed 1.0//",
+	"-//sq//dtd html 2.0 hotmetal + extensions//",
+	"-//softquad software//dtd hotmetal pro 6.0::19990601::extensions to html 4.0//",
+	"-//softquad//dtd hotmetal pro 4.0::19971010::extensions to html 4.0//",
+	"-//spyglass//dtd html 2.0 extended//",
+	"-//sun microsystems corp.//dtd hotjava html//",
+	"-//sun microsystems corp.//dtd hotjava strict html//",
+	"-//w3c//dtd html 3 1995-03-24//",
+	"-//w3c//dtd html 3.2 draft//",
+	"-//w3c//dtd html 3.2 final//",
+	"-//w3c//dtd html 3.2//",
+	"-//w3c//dtd html 3.2s draft//",
+	"-//w3c//dtd html 4.0 frameset//",
+	"-//w3c//dtd html 4.0 transitional//",
+	"-//w3c//dtd html experimental 19960712//",
+	"-//w3c//dtd html experimental 970421//",
+	"-//w3c//dtd w3 html//",
+	"-//w3o//dtd w3 html 3.0//",
+	"-//webtechs//dtd mozilla html 2.0//",
+	"-//webtechs//dtd mozilla html//",
+}
+
+// parseDoctype tokenizes the doctype at the start of an HTML document,
+// skipping a leading BOM, whitespace, comments and XML declarations.
+func parseDoctype(content []byte) doctype {
+	s := skipPrologue(content)
+	const keyword = "<!doctype"
+	if len(s) < len(keyword) || !strings.EqualFold(string(s[:len(keyword)]), keyword) {
+		return doctype{}
+	}
+	t := &doctypeTokenizer{src: s[len(keyword):]}
+	d := doctype{Present: true}
+
+	t.skipSpace()
+	d.Name = strings.ToLower(t.readName())
+	t.skipSpace()
+	switch strings.ToLower(t.readName()) {
+	case "public":
+		t.skipSpace()
+		d.PublicID = t.readQuoted()
+		t.skipSpace()
+		d.SystemID = t.readQuoted()
+	case "system":
+		t.skipSpace()
+		d.SystemID = t.readQuoted()
+	}
+	return d
+}
+
+// Version returns a readable HTML version for the doctype.
+func (d doctype) Version() string {
+	if !d.Present {
+		return "No doctype"
+	}
+	if d.Name != "html" {
+		return "Unknown"
+	}
+	public := strings.ToLower(d.PublicID)
+	if public == "" {
+		system := strings.ToLower(d.SystemID)
+		if system == "" || system == "about:legacy-compat" {
+			return "HTML5"
+		}
+		return "Unknown"
+	}
+	if version, ok := publicIDVersions[public]; ok {
+		return version
+	}
+	return "Unknown"
+}
+
+// Mode returns the document mode a browser would pick for the doctype.
+func (d doctype) Mode() string {
+	if !d.Present || d.Name != "html" {
+		return ModeQuirks
+	}
+	public := strings.ToLower(d.PublicID)
+	system := strings.ToLower(d.SystemID)
+
+	switch public {
+	case "-//w3o//dtd w3 html strict 3.0//en//", "-/w3c/dtd html 4.0 transitional/en", "html":
+		return ModeQuirks
+	}
+	if system == "http://www.ibm.com/data/dtd/v11/ibmxhtml1-transitional.dtd" {
+		return ModeQuirks
+	}
+	for _, prefix := range quirksPublicPrefixes {
+		if strings.HasPrefix(public, prefix) {
+			return ModeQuirks
+		}
+	}
+	html401Loose := strings.HasPrefix(public, "-//w3c//dtd html 4.01 frameset//") ||
+		strings.HasPrefix(public, "-//w3c//dtd html 4.01 transitional//")
+	if html401Loose && d.SystemID == "" {
+		return ModeQuirks
+	}
+	if html401Loose ||
+		strings.HasPrefix(public, "-//w3c//dtd xhtml 1.0 frameset//") ||
+		strings.HasPrefix(public, "-//w3c//dtd xhtml 1.0 transitional//") {
+		return ModeLimitedQuirks
+	}
+	return ModeNoQuirks
+}
+
+// skipPrologue drops everything that may legally precede a doctype
+func skipPrologue(s []byte) []byte {
+	s = bytes.TrimPrefix(s, []byte("\xef\xbb\xbf"))
+	for {
+		s = bytes.TrimLeft(s, " \t\r\n\f")
+		switch {
+		case bytes.HasPrefix(s, []byte("<!--")):
+			end := bytes.Index(s[4:], []byte("-->"))
+			if end < 0 {
+				return nil
+			}
+			s = s[4+end+3:]
+		case bytes.HasPrefix(s, []byte("<?")):
+			end := bytes.IndexByte(s, '>')
+			if end < 0 {
+				return nil
+			}
+			s = s[end+1:]
+		default:
+			return s
+		}
+	}
+}
+
+type doctypeTokenizer struct {
+	src []byte
+	pos int
+}
+
+func (t *doctypeTokenizer) skipSpace() {
+	for t.pos < len(t.src) && isDoctypeSpace(t.src[t.pos]) {
+		t.pos++
+	}
+}
+
+func (t *doctypeTokenizer) readName() string {
+	start := t.pos
+	for t.pos < len(t.src) && !isDoctypeSpace(t.src[t.pos]) && t.src[t.pos] != '>' &&
+		t.src[t.pos] != '"' && t.src[t.pos] != '\'' {
+		t.pos++
+	}
+	return string(t.src[start:t.pos])
+}
+
+func (t *doctypeTokenizer) readQuoted() string {
+	if t.pos >= len(t.src) || (t.src[t.pos] != '"' && t.src[t.pos] != '\'') {
+		return ""
+	}
+	quote := t.src[t.pos]
+	rest := t.src[t.pos+1:]
+	end := bytes.IndexByte(rest, quote)
+	if end < 0 {
+		// an unterminated identifier runs until the end of the tag
+		if end = bytes.IndexByte(rest, '>'); end < 0 {
+			end = len(rest)
+		}
+		t.pos += 1 + end
+		return string(rest[:end])
+	}
+	t.pos += end + 2
+	return string(rest[:end])
+}
+
+func isDoctypeSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r' || b == '\f'
+}
@@ -0,0 +1,142 @@
+package crawl
+
+import "testing"
+
+func TestParseDoctype(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    doctype
+		version string
+		mode    string
+	}{
+		{
+			name:    "html5",
+			content: "<!DOCTYPE html><html></html>",
+			want:    doctype{Present: true, Name: "html"},
+			version: "HTML5", mode: ModeNoQuirks,
+		},
+		{
+			name:    "lower case without space before the end",
+			content: "<!doctype HTML>",
+			want:    doctype{Present: true, Name: "html"},
+			version: "HTML5", mode: ModeNoQuirks,
+		},
+		{
+			name:    "legacy compat",
+			content: `<!DOCTYPE html SYSTEM "about:legacy-compat">`,
+			want:    doctype{Present: true, Name: "html", SystemID: "about:legacy-compat"},
+			version: "HTML5", mode: ModeNoQuirks,
+		},
+		{
+			name:    "missing",
+			content: "<html><head></head></html>",
+			want:    doctype{},
+			version: "No doctype", mode: ModeQuirks,
+		},
+		{
+			name:    "empty document",
+			content: "",
+			want:    doctype{},
+			version: "No doctype", mode: ModeQuirks,
+		},
+		{
+			name:    "after BOM, comments and XML declaration",
+			content: "\xef\xbb\xbf<?xml version=\"1.0\"?>\n<!-- a -- comment -->\n\t<!DOCTYPE html>",
+			want:    doctype{Present: true, Name: "html"},
+			version: "HTML5", mode: ModeNoQuirks,
+		},
+		{
+			name:    "not at the start",
+			content: "<p>text</p><!DOCTYPE html>",
+			want:    doctype{},
+			version: "No doctype", mode: ModeQuirks,
+		},
+		{
+			name:    "unterminated comment before it",
+			content: "<!-- never closed <!DOCTYPE html>",
+			want:    doctype{},
+			version: "No doctype", mode: ModeQuirks,
+		},
+		{
+			name:    "html 4.01 strict",
+			content: `<!DOCTYPE HTML PUBLIC "-//W3C//DTD HTML 4.01//EN" "http://www.w3.org/TR/html4/strict.dtd">`,
+			want:    doctype{Present: true, Name: "html", PublicID: "-//W3C//DTD HTML 4.01//EN", SystemID: "http://www.w3.org/TR/html4/strict.dtd"},
+			version: "HTML 4.01 Strict", mode: ModeNoQuirks,
+		},
+		{
+			name:    "html 4.01 transitional with system id",
+			content: `<!DOCTYPE HTML PUBLIC "-//W3C//DTD HTML 4.01 Transitional//EN" "http://www.w3.org/TR/html4/loose.dtd">`,
+			want:    doctype{Present: true, Name: "html", PublicID: "-//W3C//DTD HTML 4.01 Transitional//EN", SystemID: "http://www.w3.org/TR/html4/loose.dtd"},
+			version: "HTML 4.01 Transitional", mode: ModeLimitedQuirks,
+		},
+		{
+			name:    "html 4.01 transitional without system id",
+			content: `<!DOCTYPE HTML PUBLIC "-//W3C//DTD HTML 4.01 Transitional//EN">`,
+			want:    doctype{Present: true, Name: "html", PublicID: "-//W3C//DTD HTML 4.01 Transitional//EN"},
+			version: "HTML 4.01 Transitional", mode: ModeQuirks,
+		},
+		{
+			name:    "xhtml 1.0 transitional in single quotes",
+			content: `<!DOCTYPE html PUBLIC '-//W3C//DTD XHTML 1.0 Transitional//EN' 'http://www.w3.org/TR/xhtml1/DTD/xhtml1-transitional.dtd'>`,
+			want:    doctype{Present: true, Name: "html", PublicID: "-//W3C//DTD XHTML 1.0 Transitional//EN", SystemID: "http://www.w3.org/TR/xhtml1/DTD/xhtml1-transitional.dtd"},
+			version: "XHTML 1.0 Transitional", mode: ModeLimitedQuirks,
+		},
+		{
+			name:    "xhtml 1.1 over several lines",
+			content: "<!DOCTYPE html\n  PUBLIC \"-//W3C//DTD XHTML 1.1//EN\"\n  \"http://www.w3.org/TR/xhtml11/DTD/xhtml11.dtd\">",
+			want:    doctype{Present: true, Name: "html", PublicID: "-//W3C//DTD XHTML 1.1//EN", SystemID: "http://www.w3.org/TR/xhtml11/DTD/xhtml11.dtd"},
+			version: "XHTML 1.1", mode: ModeNoQuirks,
+		},
+		{
+			name:    "html 3.2 forces quirks",
+			content: `<!DOCTYPE HTML PUBLIC "-//W3C//DTD HTML 3.2 Final//EN">`,
+			want:    doctype{Present: true, Name: "html", PublicID: "-//W3C//DTD HTML 3.2 Final//EN"},
+			version: "HTML 3.2", mode: ModeQuirks,
+		},
+		{
+			name:    "unknown public identifier",
+			content: `<!DOCTYPE html PUBLIC "-//Example//DTD Custom//EN">`,
+			want:    doctype{Present: true, Name: "html", PublicID: "-//Example//DTD Custom//EN"},
+			version: "Unknown", mode: ModeNoQuirks,
+		},
+		{
+			name:    "unknown system identifier",
+			content: `<!DOCTYPE html SYSTEM "custom.dtd">`,
+			want:    doctype{Present: true, Name: "html", SystemID: "custom.dtd"},
+			version: "Unknown", mode: ModeNoQuirks,
+		},
+		{
+			name:    "ibm system identifier forces quirks",
+			content: `<!DOCTYPE html SYSTEM "http://www.ibm.com/data/dtd/v11/ibmxhtml1-transitional.dtd">`,
+			want:    doctype{Present: true, Name: "html", SystemID: "http://www.ibm.com/data/dtd/v11/ibmxhtml1-transitional.dtd"},
+			version: "Unknown", mode: ModeQuirks,
+		},
+		{
+			name:    "other root element",
+			content: "<!DOCTYPE svg>",
+			want:    doctype{Present: true, Name: "svg"},
+			version: "Unknown", mode: ModeQuirks,
+		},
+		{
+			name:    "unterminated identifier runs to the end of the tag",
+			content: `<!DOCTYPE html PUBLIC "-//W3C//DTD HTML 4.01//EN><html>`,
+			want:    doctype{Present: true, Name: "html", PublicID: "-//W3C//DTD HTML 4.01//EN"},
+			version: "HTML 4.01 Strict", mode: ModeNoQuirks,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseDoctype([]byte(tt.content))
+			if got != tt.want {
+				t.Fatalf("parseDoctype = %+v, want %+v", got, tt.want)
+			}
+			if version := got.Version(); version != tt.version {
+				t.Errorf("Version() = %q, want %q", version, tt.version)
+			}
+			if mode := got.Mode(); mode != tt.mode {
+				t.Errorf("Mode() = %q, want %q", mode, tt.mode)
+			}
+		})
+	}
+}
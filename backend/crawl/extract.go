@@ -0,0 +1,138 @@
+package crawl
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+	"github.com/shwetakhatra/url-analyzer/database"
+	"github.com/shwetakhatra/url-analyzer/models"
+)
+
+const (
+	// maxExtractedValues caps the values one extractor keeps per page
+	maxExtractedValues = 100
+	// maxExtractedValueLength caps the characters of a single value
+	maxExtractedValueLength = 1000
+	// maxExtractedSize caps the bytes of all values of one extractor, so
+	// they always fit their column
+	maxExtractedSize = 32 * 1024
+)
+
+// extractorAnalyzer runs the extractors of the user that apply to the URL
+type extractorAnalyzer struct{}
+
+func (extractorAnalyzer) Name() string        { return "extractors" }
+func (extractorAnalyzer) Description() string { return "User-defined field extraction" }
+func (extractorAnalyzer) HTMLOnly() bool      { return true }
+
+func (extractorAnalyzer) Analyze(in *AnalysisInput) (*AnalyzerOutput, error) {
+	found := 0
+	for _, extractor := range in.Options.Extractors {
+		value := extract(in.Doc, extractor)
+		value.URLID = in.URLID
+		if len(value.Values) > 0 {
+			found++
+		}
+		in.Result.ExtractedValues = append(in.Result.ExtractedValues, value)
+	}
+	return &AnalyzerOutput{Metrics: map[string]interface{}{
+		"extractors": len(in.Options.Extractors),
+		"found":      found,
+	}}, nil
+}
+
+func init() {
+	Register(extractorAnalyzer{})
+}
+
+// ValidateExtractor checks that an extractor can be run
+func ValidateExtractor(extractor models.Extractor) error {
+	if strings.TrimSpace(extractor.Name) == "" {
+		return errors.New("name is required")
+	}
+	if strings.TrimSpace(extractor.Selector) == "" {
+		return errors.New("selector is required")
+	}
+	if _, err := cascadia.Compile(extractor.Selector); err != nil {
+		return fmt.Errorf("invalid selector: %v", err)
+	}
+	if _, err := regexp.Compile(extractor.Pattern); err != nil {
+		return fmt.Errorf("invalid pattern: %v", err)
+	}
+	return nil
+}
+
+// extractorsFor loads the extractors of the owner of a URL that apply to it
+func extractorsFor(url models.URL) []models.Extractor {
+	var extractors []models.Extractor
+	if err := database.DB.Where("user_id = ?", url.UserID).Order("name").Find(&extractors).Error; err != nil {
+		debugLog("[DB] Error loading extractors of user %s: %v", url.UserID, err)
+		return nil
+	}
+	var applicable []models.Extractor
+	for _, extractor := range extractors {
+		if extractor.URLPattern == "" || patternRegexp(extractor.URLPattern).MatchString(url.URL) {
+			applicable = append(applicable, extractor)
+		}
+	}
+	return applicable
+}
+
+// extract collects the values of an extractor from the document. Elements
+// whose value is empty or does not match the pattern are left out. Values
+// beyond the caps are cut, which the error of the value reports.
+func extract(doc *goquery.Document, extractor models.Extractor) models.ExtractedValue {
+	value := models.ExtractedValue{ExtractorID: extractor.ID, Name: extractor.Name, Values: []string{}}
+	matcher, err := cascadia.Compile(extractor.Selector)
+	if err != nil {
+		value.Error = "invalid selector: " + err.Error()
+		return value
+	}
+	pattern, err := regexp.Compile(extractor.Pattern)
+	if err != nil {
+		value.Error = "invalid pattern: " + err.Error()
+		return value
+	}
+	size, cut := 0, false
+	doc.FindMatcher(matcher).EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		var raw string
+		if extractor.Attribute == "" {
+			raw = strings.Join(strings.Fields(s.Text()), " ")
+		} else {
+			raw = strings.TrimSpace(s.AttrOr(extractor.Attribute, ""))
+		}
+		if extractor.Pattern != "" {
+			match := pattern.FindStringSubmatch(raw)
+			switch {
+			case match == nil:
+				raw = ""
+			case len(match) > 1:
+				raw = match[1]
+			default:
+				raw = match[0]
+			}
+		}
+		if raw == "" {
+			return true
+		}
+		if runes := []rune(raw); len(runes) > maxExtractedValueLength {
+			raw = string(runes[:maxExtractedValueLength])
+			cut = true
+		}
+		if size+len(raw) > maxExtractedSize {
+			value.Error = fmt.Sprintf("values stopped at %d KB", maxExtractedSize/1024)
+			return false
+		}
+		size += len(raw)
+		value.Values = append(value.Values, raw)
+		return len(value.Values) < maxExtractedValues
+	})
+	if cut && value.Error == "" {
+		value.Error = fmt.Sprintf("values longer than %d characters were cut", maxExtractedValueLength)
+	}
+	return value
+}
@@ -0,0 +1,64 @@
+package crawl
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash/fnv"
+	"math/bits"
+	"strings"
+)
+
+// shingleSize is the number of consecutive words hashed together by SimHash
+const shingleSize = 3
+
+// contentHash identifies the main text exactly, ignoring case, punctuation
+// and whitespace
+func contentHash(words []string) string {
+	if len(words) == 0 {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(strings.Join(words, " ")))
+	return hex.EncodeToString(sum[:])
+}
+
+// simHash computes a 64-bit SimHash over the word shingles of the main text.
+// Similar texts get fingerprints that differ in few bits.
+func simHash(words []string) uint64 {
+	if len(words) == 0 {
+		return 0
+	}
+	var weights [64]int
+	add := func(shingle string) {
+		h := fnv.New64a()
+		h.Write([]byte(shingle))
+		sum := h.Sum64()
+		for bit := 0; bit < 64; bit++ {
+			if sum&(1<<bit) != 0 {
+				weights[bit]++
+			} else {
+				weights[bit]--
+			}
+		}
+	}
+	if len(words) < shingleSize {
+		add(strings.Join(words, " "))
+	}
+	for i := 0; i+shingleSize <= len(words); i++ {
+		add(strings.Join(words[i:i+shingleSize], " "))
+	}
+
+	var fingerprint uint64
+	for bit, weight := range weights {
+		if weight > 0 {
+			fingerprint |= 1 << bit
+		}
+	}
+	return fingerprint
+}
+
+// SimHashSimilarity returns how alike two SimHash fingerprints are, from 0
+// to 1, along with the number of differing bits
+func SimHashSimilarity(a, b uint64) (float64, int) {
+	distance := bits.OnesCount64(a ^ b)
+	return 1 - float64(distance)/64, distance
+}
@@ -0,0 +1,228 @@
+package crawl
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/shwetakhatra/url-analyzer/models"
+)
+
+// CategoryForms groups the findings about how forms submit their data
+const CategoryForms = "forms"
+
+var (
+	csrfFieldName   = regexp.MustCompile(`(?i)csrf|xsrf|authenticity_token|^_token$|requestverificationtoken|^nonce$|formkey`)
+	paymentField    = regexp.MustCompile(`(?i)card.?(number|num|no)|^cc[-_]|cvv|cvc|card.?exp|security.?code`)
+	searchField     = regexp.MustCompile(`(?i)^(q|s|query|search|keywords?|term)$`)
+	confirmField    = regexp.MustCompile(`(?i)confirm|repeat|again|verify|password2|re.?password`)
+	signupWording   = regexp.MustCompile(`(?i)sign.?up|register|registration|create.?(an.?)?account|join`)
+	newsletterWords = regexp.MustCompile(`(?i)newsletter|subscribe|subscription|mailing`)
+	contactWording  = regexp.MustCompile(`(?i)contact|enquir|inquir|message|feedback|support`)
+)
+
+// input types that do not carry user data
+var nonDataInputTypes = map[string]bool{
+	"submit": true,
+	"button": true,
+	"reset":  true,
+	"image":  true,
+}
+
+type formField struct {
+	Name      string
+	Type      string
+	Value     string
+	Selection *goquery.Selection
+}
+
+// formInfo is a form of the page as seen by the form analyzer and the
+// scripted login
+type formInfo struct {
+	Selection *goquery.Selection
+	Method    string
+	Action    *url.URL
+	Fields    []formField
+	Kind      string
+}
+
+func (f formInfo) hasType(fieldType string) bool {
+	return f.countType(fieldType) > 0
+}
+
+func (f formInfo) countType(fieldType string) int {
+	n := 0
+	for _, field := range f.Fields {
+		if field.Type == fieldType {
+			n++
+		}
+	}
+	return n
+}
+
+// detectForms lists the forms of a page with their fields and classifies each one
+func detectForms(doc *goquery.Document, base *url.URL) []formInfo {
+	var forms []formInfo
+	doc.Find("form").Each(func(_ int, s *goquery.Selection) {
+		form := formInfo{
+			Selection: s,
+			Method:    strings.ToUpper(strings.TrimSpace(s.AttrOr("method", ""))),
+			Action:    base,
+		}
+		if form.Method != "POST" && form.Method != "DIALOG" {
+			form.Method = "GET"
+		}
+		if action := strings.TrimSpace(s.AttrOr("action", "")); action != "" {
+			if ref, err := base.Parse(action); err == nil {
+				form.Action = ref
+			}
+		}
+		fields := s.Find("input, select, textarea")
+		if id := s.AttrOr("id", ""); id != "" {
+			// controls placed outside the form element but owned through form=""
+			fields = fields.AddSelection(doc.Find(fmt.Sprintf(`[form=%q]`, id)))
+		}
+		fields.Each(func(_ int, field *goquery.Selection) {
+			fieldType := goquery.NodeName(field)
+			if fieldType == "input" {
+				fieldType = strings.ToLower(strings.TrimSpace(field.AttrOr("type", "text")))
+				if fieldType == "" {
+					fieldType = "text"
+				}
+			}
+			if nonDataInputTypes[fieldType] {
+				return
+			}
+			form.Fields = append(form.Fields, formField{
+				Name:      field.AttrOr("name", ""),
+				Type:      fieldType,
+				Value:     field.AttrOr("value", ""),
+				Selection: field,
+			})
+		})
+		form.Kind = classifyForm(form)
+		forms = append(forms, form)
+	})
+	return forms
+}
+
+// classifyForm guesses what a form is for from its fields and wording
+func classifyForm(form formInfo) string {
+	var hints strings.Builder
+	for _, attr := range []string{"id", "name", "class", "action", "aria-label"} {
+		hints.WriteString(form.Selection.AttrOr(attr, "") + " ")
+	}
+	form.Selection.Find(`button, input[type="submit"], legend, h1, h2, h3, h4, label`).Each(func(_ int, s *goquery.Selection) {
+		hints.WriteString(s.Text() + " " + s.AttrOr("value", "") + " ")
+	})
+	wording := hints.String()
+
+	var names []string
+	for _, field := range form.Fields {
+		names = append(names, field.Name+" "+field.Selection.AttrOr("autocomplete", "")+" "+field.Selection.AttrOr("id", ""))
+	}
+	fieldText := strings.Join(names, " ")
+
+	passwords := form.countType("password")
+	dataFields := 0
+	emailFields := 0
+	for _, field := range form.Fields {
+		if field.Type == "hidden" {
+			continue
+		}
+		dataFields++
+		if field.Type == "email" || strings.Contains(strings.ToLower(field.Name), "email") {
+			emailFields++
+		}
+	}
+
+	switch {
+	case paymentField.MatchString(fieldText):
+		return models.FormPayment
+	case passwords > 1 || (passwords == 1 && (signupWording.MatchString(wording) || confirmField.MatchString(fieldText))):
+		return models.FormSignup
+	case passwords == 1:
+		return models.FormLogin
+	case form.hasType("search") || form.Selection.AttrOr("role", "") == "search" ||
+		(dataFields == 1 && anyFieldName(form, searchField)) || strings.Contains(strings.ToLower(form.Action.Path), "search"):
+		return models.FormSearch
+	case form.hasType("textarea") && (emailFields > 0 || contactWording.MatchString(wording)):
+		return models.FormContact
+	case emailFields == 1 && (dataFields <= 2 || newsletterWords.MatchString(wording)):
+		return models.FormNewsletter
+	case contactWording.MatchString(wording) && form.hasType("textarea"):
+		return models.FormContact
+	}
+	return models.FormUnknown
+}
+
+func anyFieldName(form formInfo, pattern *regexp.Regexp) bool {
+	for _, field := range form.Fields {
+		if field.Type != "hidden" && pattern.MatchString(field.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// analyzeForms records every form of the page and flags forms that send
+// their data over plain HTTP or put credentials in the URL
+func analyzeForms(doc *goquery.Document, pageURL *url.URL, urlID string, result *CrawlResult) {
+	paths := newCSSPaths(doc)
+	add := func(code, severity, message string, s *goquery.Selection) {
+		result.Findings = append(result.Findings, models.Finding{
+			URLID:     urlID,
+			Category:  CategoryForms,
+			Code:      code,
+			Severity:  severity,
+			Message:   message,
+			Selector:  paths.of(s),
+			Element:   "form",
+			Attribute: "action",
+		})
+	}
+
+	for i, form := range detectForms(doc, documentBase(doc, pageURL)) {
+		record := models.Form{
+			URLID:      urlID,
+			Position:   i,
+			Kind:       form.Kind,
+			Method:     form.Method,
+			Action:     form.Action.String(),
+			FieldTypes: []string{},
+			FieldNames: []string{},
+			Selector:   paths.of(form.Selection),
+		}
+		for _, field := range form.Fields {
+			record.FieldTypes = append(record.FieldTypes, field.Type)
+			record.FieldNames = append(record.FieldNames, field.Name)
+			if field.Type == "hidden" && csrfFieldName.MatchString(field.Name) {
+				record.HasCSRFToken = true
+			}
+		}
+		sensitive := form.hasType("password") || form.Kind == models.FormPayment
+
+		if form.Action.Scheme == "http" {
+			record.InsecureAction = true
+			severity := models.SeverityWarning
+			if sensitive {
+				severity = models.SeverityError
+			}
+			add("form_submits_over_http", severity,
+				fmt.Sprintf("%s form submits over plain HTTP to %s", form.Kind, form.Action), form.Selection)
+		}
+		if form.Method == "GET" && sensitive {
+			record.CredentialsInGET = true
+			add("credentials_in_get", models.SeverityError,
+				fmt.Sprintf("%s form sends sensitive fields with GET, exposing them in the URL", form.Kind), form.Selection)
+		}
+		if form.Method == "POST" && !record.HasCSRFToken &&
+			(form.Kind == models.FormLogin || form.Kind == models.FormSignup || form.Kind == models.FormPayment) {
+			add("missing_csrf_token", models.SeverityNotice,
+				fmt.Sprintf("%s form has no recognizable CSRF token field", form.Kind), form.Selection)
+		}
+		result.Forms = append(result.Forms, record)
+	}
+}
@@ -0,0 +1,117 @@
+package crawl
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/shwetakhatra/url-analyzer/models"
+)
+
+// fragmentChecker tells whether the fragment of a link points at an element
+// of its target document. The anchors of every fetched target are cached so
+// that many links into the same page cost a single request.
+type fragmentChecker struct {
+	page    *url.URL
+	anchors map[string]map[string]bool
+	// history replaces fetching other pages in an offline analysis
+	history *CheckHistory
+	// request is sent when fetching other pages of the site
+	request *models.RequestOptions
+}
+
+func newFragmentChecker(doc *goquery.Document, pageURL *url.URL, opts CrawlOptions) *fragmentChecker {
+	return &fragmentChecker{
+		page:    withoutFragment(pageURL),
+		anchors: map[string]map[string]bool{withoutFragment(pageURL).String(): anchorTargets(doc)},
+		history: opts.History,
+		request: linkOptions(opts, true),
+	}
+}
+
+// samePage tells whether a link points into the analyzed page itself
+func (fc *fragmentChecker) samePage(ref *url.URL) bool {
+	return withoutFragment(ref).String() == fc.page.String()
+}
+
+// exists reports whether the fragment of ref names an id or anchor in the
+// target document. Links without a checkable fragment and targets that are
+// not HTML or could not be fetched count as valid.
+func (fc *fragmentChecker) exists(ref *url.URL) bool {
+	fragment := ref.EscapedFragment()
+	if fragment == "" || strings.EqualFold(fragment, "top") ||
+		strings.HasPrefix(fragment, "!") || strings.HasPrefix(fragment, "/") {
+		// empty and "top" scroll to the top, the rest are client-side routes
+		return true
+	}
+	target := withoutFragment(ref).String()
+	anchors, ok := fc.anchors[target]
+	if !ok && fc.history != nil {
+		return !fc.history.fragmentBroken(ref)
+	}
+	if !ok {
+		var options *models.RequestOptions
+		if strings.EqualFold(ref.Hostname(), fc.page.Hostname()) {
+			options = fc.request
+		}
+		anchors = fetchAnchors(target, options)
+		fc.anchors[target] = anchors
+	}
+	if anchors == nil {
+		return true
+	}
+	return anchors[fragment] || anchors[ref.Fragment]
+}
+
+// fetchAnchors downloads an HTML document and returns its anchors, or nil
+// when the target cannot be fetched or is not HTML
+func fetchAnchors(target string, options *models.RequestOptions) map[string]bool {
+	req, err := newRequest(http.MethodGet, target, options)
+	if err != nil {
+		return nil
+	}
+	resp, err := linkClient.Do(req)
+	if err != nil {
+		return nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return nil
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	if err != nil || !isHTMLKind(documentKind(detectContentType(resp.Header.Get("Content-Type"), body), body)) {
+		return nil
+	}
+	if body, _, err = decodeBody(body, resp.Header.Get("Content-Type")); err != nil {
+		return nil
+	}
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return nil
+	}
+	return anchorTargets(doc)
+}
+
+// anchorTargets collects what a fragment can point at: element ids and the
+// names of a elements
+func anchorTargets(doc *goquery.Document) map[string]bool {
+	anchors := map[string]bool{}
+	doc.Find("[id]").Each(func(_ int, s *goquery.Selection) {
+		anchors[s.AttrOr("id", "")] = true
+	})
+	doc.Find("a[name]").Each(func(_ int, s *goquery.Selection) {
+		anchors[s.AttrOr("name", "")] = true
+	})
+	delete(anchors, "")
+	return anchors
+}
+
+func withoutFragment(u *url.URL) *url.URL {
+	stripped := *u
+	stripped.Fragment = ""
+	stripped.RawFragment = ""
+	return &stripped
+}
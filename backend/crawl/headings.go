@@ -0,0 +1,105 @@
+package crawl
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/shwetakhatra/url-analyzer/models"
+)
+
+// CategoryHeadings groups the findings about the heading outline
+const CategoryHeadings = "headings"
+
+// HeadingNode is one entry of the nested heading outline
+type HeadingNode struct {
+	Level    int            `json:"level"`
+	Text     string         `json:"text"`
+	Position int            `json:"position"`
+	Children []*HeadingNode `json:"children"`
+}
+
+// analyzeHeadings collects the H1–H6 outline in document order, fills the
+// per-level counts and reports structural problems with the outline.
+func analyzeHeadings(doc *goquery.Document, urlID string, result *CrawlResult) {
+	var counts [7]int
+	doc.Find("h1, h2, h3, h4, h5, h6").Each(func(i int, s *goquery.Selection) {
+		level := int(goquery.NodeName(s)[1] - '0')
+		counts[level]++
+		result.Headings = append(result.Headings, models.Heading{
+			URLID:    urlID,
+			Level:    level,
+			Text:     headingText(s),
+			Position: i,
+		})
+	})
+	result.H1Count, result.H2Count, result.H3Count = counts[1], counts[2], counts[3]
+	result.H4Count, result.H5Count, result.H6Count = counts[4], counts[5], counts[6]
+
+	addIssue := func(code, severity, message string) {
+		result.Findings = append(result.Findings, models.Finding{
+			URLID:    urlID,
+			Category: CategoryHeadings,
+			Code:     code,
+			Severity: severity,
+			Message:  message,
+		})
+	}
+
+	switch {
+	case counts[1] == 0:
+		addIssue("missing_h1", models.SeverityError, "Page has no H1 heading")
+	case counts[1] > 1:
+		addIssue("multiple_h1", models.SeverityWarning, fmt.Sprintf("Page has %d H1 headings", counts[1]))
+	}
+
+	previous := 0
+	for _, h := range result.Headings {
+		if h.Text == "" {
+			addIssue("empty_heading", models.SeverityWarning,
+				fmt.Sprintf("H%d heading at position %d has no text", h.Level, h.Position))
+		}
+		if previous > 0 && h.Level > previous+1 {
+			addIssue("skipped_level", models.SeverityWarning,
+				fmt.Sprintf("H%d at position %d follows H%d, skipping a level", h.Level, h.Position, previous))
+		}
+		previous = h.Level
+	}
+}
+
+// headingText returns the visible text of a heading, falling back to the
+// alt text of images so that logo headings do not count as empty
+func headingText(s *goquery.Selection) string {
+	text := strings.Join(strings.Fields(s.Text()), " ")
+	if text != "" {
+		return text
+	}
+	var alts []string
+	s.Find("img[alt]").Each(func(_ int, img *goquery.Selection) {
+		if alt := strings.TrimSpace(img.AttrOr("alt", "")); alt != "" {
+			alts = append(alts, alt)
+		}
+	})
+	return strings.Join(alts, " ")
+}
+
+// HeadingOutline nests a flat, ordered list of headings into a tree where
+// each heading owns the following headings of a deeper level.
+func HeadingOutline(headings []models.Heading) []*HeadingNode {
+	roots := []*HeadingNode{}
+	var stack []*HeadingNode
+	for _, h := range headings {
+		node := &HeadingNode{Level: h.Level, Text: h.Text, Position: h.Position, Children: []*HeadingNode{}}
+		for len(stack) > 0 && stack[len(stack)-1].Level >= h.Level {
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) == 0 {
+			roots = append(roots, node)
+		} else {
+			parent := stack[len(stack)-1]
+			parent.Children = append(parent.Children, node)
+		}
+		stack = append(stack, node)
+	}
+	return roots
+}
@@ -0,0 +1,94 @@
+package crawl
+
+import (
+	"net/url"
+
+	"github.com/shwetakhatra/url-analyzer/database"
+	"github.com/shwetakhatra/url-analyzer/models"
+)
+
+// CheckHistory holds the link and resource checks of the last crawl of a
+// URL. An offline analysis reuses them instead of requesting the targets
+// again: links not recorded as broken are taken to be fine.
+type CheckHistory struct {
+	brokenLinks     map[string]int
+	brokenFragments map[string]bool
+	resources       map[string]models.Resource
+}
+
+// LoadCheckHistory reads the check results currently stored for a URL
+func LoadCheckHistory(urlID string) (*CheckHistory, error) {
+	history := newCheckHistory()
+	var links []models.BrokenLink
+	if err := database.DB.Where("url_id = ?", urlID).Find(&links).Error; err != nil {
+		return nil, err
+	}
+	for _, link := range links {
+		switch link.Type {
+		case models.BrokenLinkTypeLink, "":
+			history.brokenLinks[link.Link] = link.Status
+		case models.BrokenLinkTypeFragment:
+			history.brokenFragments[normalizeLink(link.Link)] = true
+		}
+	}
+	var resources []models.Resource
+	if err := database.DB.Where("url_id = ?", urlID).Find(&resources).Error; err != nil {
+		return nil, err
+	}
+	for _, res := range resources {
+		history.resources[res.Link] = res
+	}
+	return history, nil
+}
+
+// newCheckHistory returns a history without checks, which takes every
+// link to be fine
+func newCheckHistory() *CheckHistory {
+	return &CheckHistory{
+		brokenLinks:     map[string]int{},
+		brokenFragments: map[string]bool{},
+		resources:       map[string]models.Resource{},
+	}
+}
+
+func (h *CheckHistory) linkStatus(link string) (int, bool) {
+	if status, broken := h.brokenLinks[link]; broken {
+		return status, false
+	}
+	return 0, true
+}
+
+func (h *CheckHistory) fragmentBroken(ref *url.URL) bool {
+	return h.brokenFragments[ref.String()]
+}
+
+// restoreResource copies the earlier check of a resource, if there was one
+func (h *CheckHistory) restoreResource(res *models.Resource) {
+	if previous, ok := h.resources[res.Link]; ok && previous.Checked {
+		res.Checked = true
+		res.Status = previous.Status
+		res.Size = previous.Size
+		res.ContentType = previous.ContentType
+	}
+}
+
+// fonts returns the fonts an earlier check found in the stylesheets
+func (h *CheckHistory) fonts() []*url.URL {
+	var fonts []*url.URL
+	for link, res := range h.resources {
+		if res.Type != ResourceFont {
+			continue
+		}
+		if ref, err := url.Parse(link); err == nil {
+			fonts = append(fonts, ref)
+		}
+	}
+	return fonts
+}
+
+func normalizeLink(link string) string {
+	if ref, err := url.Parse(link); err == nil {
+		return ref.String()
+	}
+	return link
+}
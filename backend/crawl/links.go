@@ -0,0 +1,76 @@
+package crawl
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/shwetakhatra/url-analyzer/models"
+)
+
+// analyzeLinks counts the internal and external links of the page, checks
+// them and validates their fragments
+func analyzeLinks(doc *goquery.Document, page *Page, opts CrawlOptions, urlID string, result *CrawlResult) {
+	internal, external, broken, brokenFragments := 0, 0, 0, 0
+	base := page.URL.Hostname()
+	docBase := documentBase(doc, page.URL)
+	fragments := newFragmentChecker(doc, page.URL, opts)
+	brokenFragment := func(href string, status int) {
+		brokenFragments++
+		result.BrokenLinks = append(result.BrokenLinks, models.BrokenLink{
+			URLID:  urlID,
+			Link:   href,
+			Status: status,
+			Type:   models.BrokenLinkTypeFragment,
+		})
+	}
+
+	links := map[string]bool{}
+
+	doc.Find("a[href]").Each(func(i int, s *goquery.Selection) {
+		href, _ := s.Attr("href")
+		ref, err := docBase.Parse(strings.TrimSpace(href))
+		// jumps within the page are checked against the page itself
+		samePage := err == nil && ref.Fragment != "" && fragments.samePage(ref)
+		if samePage && !fragments.exists(ref) {
+			brokenFragment(href, page.StatusCode)
+		}
+		// relative links count as well, resolved against the document base
+		if err != nil || (ref.Scheme != "http" && ref.Scheme != "https") {
+			return
+		}
+		link := ref.String()
+		links[link] = true
+		isInternal := strings.EqualFold(ref.Hostname(), base)
+		if isInternal {
+			internal++
+		} else {
+			external++
+		}
+		if samePage {
+			return
+		}
+		// request options only go to the host of the page itself
+		status, ok := checkLink(link, isInternal, opts)
+		if !ok {
+			broken++
+			result.BrokenLinks = append(result.BrokenLinks, models.BrokenLink{
+				URLID:  urlID,
+				Link:   link,
+				Status: status,
+				Type:   models.BrokenLinkTypeLink,
+			})
+		} else if isInternal && !fragments.exists(ref) {
+			brokenFragment(link, status)
+		}
+	})
+
+	result.InternalLinks = internal
+	result.ExternalLinks = external
+	result.BrokenLinkCount = broken
+	result.BrokenFragmentCount = brokenFragments
+	for link := range links {
+		result.Links = append(result.Links, link)
+	}
+	sort.Strings(result.Links)
+}
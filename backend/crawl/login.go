@@ -0,0 +1,257 @@
+package crawl
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+	"github.com/shwetakhatra/url-analyzer/models"
+	"golang.org/x/net/publicsuffix"
+)
+
+// ValidateLoginRecipe checks that a login recipe can be carried out
+func ValidateLoginRecipe(recipe models.LoginRecipe) error {
+	loginURL, err := url.Parse(recipe.LoginURL)
+	if err != nil || (loginURL.Scheme != "http" && loginURL.Scheme != "https") || loginURL.Host == "" {
+		return errors.New("login_url must be an absolute http or https URL")
+	}
+	if recipe.Password == "" {
+		return errors.New("password is required")
+	}
+	for _, selector := range []string{
+		recipe.FormSelector, recipe.UsernameSelector, recipe.PasswordSelector,
+		recipe.SuccessSelector, recipe.FailureSelector,
+	} {
+		if selector == "" {
+			continue
+		}
+		if _, err := cascadia.Compile(selector); err != nil {
+			return fmt.Errorf("invalid selector %q: %v", selector, err)
+		}
+	}
+	return nil
+}
+
+// pageSession returns the client to fetch a page with: a client logged in
+// with the login recipe if there is one, the verifying page client if the
+// URL has request options, or else the page client. The options are sent
+// along with the login requests.
+func pageSession(opts CrawlOptions, options *models.RequestOptions) (*http.Client, error) {
+	if opts.Login != nil {
+		return login(opts.Login, options)
+	}
+	if !opts.Request.Empty() {
+		return securePageClient, nil
+	}
+	return pageClient, nil
+}
+
+// login carries out a login recipe and returns a client holding the
+// session cookies. The request options go along with every request, which
+// all verify the certificate of the server.
+func login(recipe *models.LoginRecipe, options *models.RequestOptions) (*http.Client, error) {
+	jar, err := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{
+		Transport:     securePageClient.Transport,
+		CheckRedirect: securePageClient.CheckRedirect,
+		Jar:           jar,
+		Timeout:       pageTimeout,
+	}
+
+	req, err := newRequest(http.MethodGet, recipe.LoginURL, options)
+	if err != nil {
+		return nil, err
+	}
+	doc, resp, err := fetchDocument(client, req)
+	if err != nil {
+		return nil, fmt.Errorf("could not load login page: %w", err)
+	}
+	form, err := findLoginForm(doc, resp.Request.URL, recipe)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkLoginAction(form.Action, recipe.LoginURL, resp.Request.URL); err != nil {
+		return nil, err
+	}
+	values, err := loginValues(form, recipe)
+	if err != nil {
+		return nil, err
+	}
+
+	target := *form.Action
+	if form.Method == "GET" {
+		target.RawQuery = values.Encode()
+		req, err = newRequest(http.MethodGet, target.String(), options)
+	} else {
+		req, err = newRequest(http.MethodPost, target.String(), options)
+		if err == nil {
+			req.Body = io.NopCloser(strings.NewReader(values.Encode()))
+			req.ContentLength = int64(len(values.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Referer", resp.Request.URL.String())
+	doc, resp, err = fetchDocument(client, req)
+	if err != nil {
+		return nil, fmt.Errorf("login failed: %w", err)
+	}
+	if err := checkLogin(doc, resp.Request.URL, recipe); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// fetchDocument sends a request and parses the HTML response
+func fetchDocument(client *http.Client, req *http.Request) (*goquery.Document, *http.Response, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return nil, resp, fmt.Errorf("server answered %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	if err != nil {
+		return nil, resp, err
+	}
+	if body, _, err = decodeBody(body, resp.Header.Get("Content-Type")); err != nil {
+		return nil, resp, err
+	}
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, resp, err
+	}
+	return doc, resp, nil
+}
+
+// findLoginForm picks the form of the recipe, or else the first login form
+// of the page
+func findLoginForm(doc *goquery.Document, pageURL *url.URL, recipe *models.LoginRecipe) (formInfo, error) {
+	forms := detectForms(doc, documentBase(doc, pageURL))
+	for _, form := range forms {
+		if recipe.FormSelector != "" {
+			if form.Selection.Is(recipe.FormSelector) {
+				return form, nil
+			}
+		} else if form.Kind == models.FormLogin || form.hasType("password") {
+			return form, nil
+		}
+	}
+	if recipe.FormSelector != "" {
+		return formInfo{}, fmt.Errorf("login page has no form matching %q", recipe.FormSelector)
+	}
+	return formInfo{}, errors.New("login page has no login form")
+}
+
+// checkLoginAction refuses to submit the credentials to another host than
+// the one of the login URL, or over plain HTTP when the login page was
+// loaded over HTTPS
+func checkLoginAction(action *url.URL, loginURL string, pageURL *url.URL) error {
+	login, err := url.Parse(loginURL)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(action.Hostname(), login.Hostname()) {
+		return fmt.Errorf("login form submits to %s, not to the host of the login URL", action.Host)
+	}
+	if action.Scheme != "https" && (login.Scheme == "https" || pageURL.Scheme == "https") {
+		return errors.New("login form submits over plain HTTP from an HTTPS page")
+	}
+	return nil
+}
+
+// loginValues fills in a form as a browser would submit it, with the
+// credentials and the extra fields of the recipe
+func loginValues(form formInfo, recipe *models.LoginRecipe) (url.Values, error) {
+	values := url.Values{}
+	var username, password *formField
+	for i := range form.Fields {
+		field := &form.Fields[i]
+		switch {
+		case recipe.PasswordSelector != "" && field.Selection.Is(recipe.PasswordSelector),
+			recipe.PasswordSelector == "" && password == nil && field.Type == "password":
+			password = field
+		case recipe.UsernameSelector != "" && field.Selection.Is(recipe.UsernameSelector),
+			recipe.UsernameSelector == "" && username == nil && password == nil &&
+				(field.Type == "text" || field.Type == "email" || field.Type == "tel"):
+			username = field
+		}
+		if field.Name == "" {
+			continue
+		}
+		switch field.Type {
+		case "checkbox", "radio":
+			if field.Selection.Is("[checked]") {
+				values.Add(field.Name, field.Selection.AttrOr("value", "on"))
+			}
+		case "select":
+			option := field.Selection.Find("option[selected]").First()
+			if option.Length() == 0 {
+				option = field.Selection.Find("option").First()
+			}
+			if option.Length() > 0 {
+				values.Add(field.Name, option.AttrOr("value", strings.TrimSpace(option.Text())))
+			}
+		case "textarea":
+			values.Add(field.Name, field.Selection.Text())
+		default:
+			values.Add(field.Name, field.Value)
+		}
+	}
+	if password == nil || password.Name == "" {
+		return nil, errors.New("login form has no password field")
+	}
+	values.Set(password.Name, recipe.Password)
+	if recipe.Username != "" {
+		if username == nil || username.Name == "" {
+			return nil, errors.New("login form has no username field")
+		}
+		values.Set(username.Name, recipe.Username)
+	}
+	for name, value := range recipe.Fields {
+		values.Set(name, value)
+	}
+	return values, nil
+}
+
+// checkLogin tells from the page shown after submitting the form whether
+// the login worked
+func checkLogin(doc *goquery.Document, pageURL *url.URL, recipe *models.LoginRecipe) error {
+	if recipe.FailureSelector != "" && doc.Find(recipe.FailureSelector).Length() > 0 {
+		return fmt.Errorf("login failed: page shows %q after submitting", recipe.FailureSelector)
+	}
+	if recipe.SuccessSelector != "" {
+		if doc.Find(recipe.SuccessSelector).Length() == 0 {
+			return fmt.Errorf("login failed: %q not found after submitting", recipe.SuccessSelector)
+		}
+		return nil
+	}
+	if _, err := findLoginForm(doc, pageURL, recipe); err == nil {
+		return errors.New("login failed: the login form is shown again after submitting")
+	}
+	return nil
+}
+
+// backOnLoginPage tells whether fetching the target after logging in ended
+// up on the login page, which means the session was not accepted
+func backOnLoginPage(page *Page, recipe *models.LoginRecipe, target string) bool {
+	loginURL, err := url.Parse(recipe.LoginURL)
+	if err != nil || strings.TrimSuffix(target, "/") == strings.TrimSuffix(recipe.LoginURL, "/") {
+		return false
+	}
+	return page.URL.Host == loginURL.Host && page.URL.Path == loginURL.Path
+}
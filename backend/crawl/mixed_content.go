@@ -0,0 +1,68 @@
+package crawl
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/shwetakhatra/url-analyzer/models"
+)
+
+// Categories of the mixed content and subresource integrity checks
+const (
+	CategoryMixedContent = "mixed_content"
+	CategorySRI          = "sri"
+)
+
+// activeMixedContent are resource types browsers block outright when they
+// are loaded over HTTP from an HTTPS page
+var activeMixedContent = map[string]bool{
+	ResourceScript:     true,
+	ResourceStylesheet: true,
+	ResourceFont:       true,
+	ResourceIframe:     true,
+	ResourceObject:     true,
+	ResourceForm:       true,
+}
+
+// analyzeMixedContent reports HTTP subresources and form targets on HTTPS
+// pages, and third-party scripts and stylesheets without an integrity hash
+func analyzeMixedContent(doc *goquery.Document, pageURL *url.URL, urlID string, result *CrawlResult) {
+	paths := newCSSPaths(doc)
+	add := func(category, code, severity, message string, ref resourceRef) {
+		result.Findings = append(result.Findings, models.Finding{
+			URLID:     urlID,
+			Category:  category,
+			Code:      code,
+			Severity:  severity,
+			Message:   message,
+			Selector:  paths.of(ref.Selection),
+			Element:   goquery.NodeName(ref.Selection),
+			Attribute: ref.Attribute,
+		})
+	}
+
+	for _, ref := range subresourceRefs(doc, documentBase(doc, pageURL)) {
+		if pageURL.Scheme == "https" && ref.URL.Scheme == "http" {
+			switch {
+			case ref.Type == ResourceForm:
+				add(CategoryMixedContent, "insecure_form_action", models.SeverityError,
+					"Form submits over plain HTTP to "+ref.URL.String(), ref)
+			case activeMixedContent[ref.Type]:
+				add(CategoryMixedContent, "active_mixed_content", models.SeverityError,
+					fmt.Sprintf("%s loaded over plain HTTP: %s", ref.Type, ref.URL), ref)
+			default:
+				add(CategoryMixedContent, "passive_mixed_content", models.SeverityWarning,
+					fmt.Sprintf("%s loaded over plain HTTP: %s", ref.Type, ref.URL), ref)
+			}
+		}
+
+		isCode := ref.Type == ResourceScript || ref.Type == ResourceStylesheet
+		if isCode && !strings.EqualFold(ref.URL.Hostname(), pageURL.Hostname()) &&
+			strings.TrimSpace(ref.Selection.AttrOr("integrity", "")) == "" {
+			add(CategorySRI, "missing_integrity", models.SeverityWarning,
+				fmt.Sprintf("Third-party %s from %s has no integrity attribute", ref.Type, ref.URL.Hostname()), ref)
+		}
+	}
+}
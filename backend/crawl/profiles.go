@@ -0,0 +1,204 @@
+package crawl
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/shwetakhatra/url-analyzer/models"
+)
+
+// UserAgentProfile is a browser or bot a URL can be crawled as
+type UserAgentProfile struct {
+	Name      string `json:"name"`
+	UserAgent string `json:"user_agent"`
+}
+
+// Profiles are the user agent profiles URLs can be compared with
+var Profiles = []UserAgentProfile{
+	{"desktop", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Safari/537.36"},
+	{"mobile", "Mozilla/5.0 (Linux; Android 10; K) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Mobile Safari/537.36"},
+	{"iphone", "Mozilla/5.0 (iPhone; CPU iPhone OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Mobile/15E148 Safari/604.1"},
+	{"googlebot", "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)"},
+	{"googlebot_mobile", "Mozilla/5.0 (Linux; Android 6.0.1; Nexus 5X Build/MMB29P) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Mobile Safari/537.36 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)"},
+	{"bingbot", "Mozilla/5.0 (compatible; bingbot/2.0; +http://www.bing.com/bingbot.htm)"},
+}
+
+// profileAnalyzers are the analyzers a profile crawl runs, offline
+var profileAnalyzers = map[string]bool{"headings": true, "content": true, "links": true}
+
+// profileTolerance is the relative difference in content length and word
+// count that profiles may have before it counts as a difference, since
+// pages often vary slightly from one request to the next
+const profileTolerance = 0.1
+
+// FindProfile looks up a user agent profile by name
+func FindProfile(name string) (UserAgentProfile, bool) {
+	for _, profile := range Profiles {
+		if profile.Name == name {
+			return profile, true
+		}
+	}
+	return UserAgentProfile{}, false
+}
+
+// crawlProfiles fetches a page once per profile. Failures are recorded in
+// the result of the profile.
+func crawlProfiles(rawURL, urlID string, names []string, opts CrawlOptions) []models.ProfileResult {
+	var results []models.ProfileResult
+	for _, name := range names {
+		profile, ok := FindProfile(name)
+		if !ok {
+			continue
+		}
+		results = append(results, crawlProfile(rawURL, urlID, profile, opts))
+	}
+	return results
+}
+
+// crawlProfile fetches a page with the user agent of a profile, in the
+// session of the login recipe if there is one, and analyzes what can
+// differ between user agents without checking any links
+func crawlProfile(rawURL, urlID string, profile UserAgentProfile, opts CrawlOptions) models.ProfileResult {
+	res := models.ProfileResult{
+		URLID:     urlID,
+		Profile:   profile.Name,
+		UserAgent: profile.UserAgent,
+		Redirects: []models.Redirect{},
+		Headings:  []string{},
+	}
+	options := withHeaders(opts.Request, map[string]string{"User-Agent": profile.UserAgent})
+
+	client, err := pageSession(opts, options)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	page, err := fetchResponse(client, rawURL, options)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	res.StatusCode = page.StatusCode
+	res.FinalURL = page.URL.String()
+	res.ContentLength = len(page.Body)
+	res.FetchedAt = page.FetchedAt
+	if page.Redirects != nil {
+		res.Redirects = page.Redirects
+	}
+
+	disabled := map[string]bool{}
+	for _, a := range Analyzers() {
+		disabled[a.Name()] = !profileAnalyzers[a.Name()]
+	}
+	result, err := AnalyzePage(page, urlID, CrawlOptions{History: newCheckHistory(), Disabled: disabled})
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	res.Title = result.Title
+	for _, heading := range result.Headings {
+		res.Headings = append(res.Headings, fmt.Sprintf("h%d %s", heading.Level, heading.Text))
+	}
+	res.InternalLinks = result.InternalLinks
+	res.ExternalLinks = result.ExternalLinks
+	res.WordCount = result.WordCount
+	res.ContentHash = result.ContentHash
+	return res
+}
+
+// ProfileDifference is a field on which at least one profile differs from
+// the base profile, with the value of every profile
+type ProfileDifference struct {
+	Field  string                 `json:"field"`
+	Values map[string]interface{} `json:"values"`
+}
+
+// ProfileComparison compares the results of several profiles with those of
+// a base profile
+type ProfileComparison struct {
+	Base        string                 `json:"base"`
+	Profiles    []models.ProfileResult `json:"profiles"`
+	Differences []ProfileDifference    `json:"differences"`
+	// Headings lists per profile the headings it has and the base has not
+	// and the other way round
+	Headings map[string]SetChange `json:"headings"`
+}
+
+// CompareProfiles compares profile results with the one of the base
+// profile, or the first one if base is empty
+func CompareProfiles(results []models.ProfileResult, base string) (ProfileComparison, error) {
+	comparison := ProfileComparison{
+		Base:        base,
+		Profiles:    results,
+		Differences: []ProfileDifference{},
+		Headings:    map[string]SetChange{},
+	}
+	var baseResult *models.ProfileResult
+	for i := range results {
+		if results[i].Profile == base || (base == "" && i == 0) {
+			baseResult = &results[i]
+		}
+	}
+	if baseResult == nil {
+		return comparison, fmt.Errorf("no result for profile %q", base)
+	}
+	comparison.Base = baseResult.Profile
+
+	fields := []struct {
+		name   string
+		value  func(r models.ProfileResult) interface{}
+		differ func(a, b models.ProfileResult) bool
+	}{
+		{"status_code", func(r models.ProfileResult) interface{} { return r.StatusCode },
+			func(a, b models.ProfileResult) bool { return a.StatusCode != b.StatusCode }},
+		{"final_url", func(r models.ProfileResult) interface{} { return r.FinalURL },
+			func(a, b models.ProfileResult) bool { return a.FinalURL != b.FinalURL }},
+		{"redirects", func(r models.ProfileResult) interface{} { return r.Redirects },
+			func(a, b models.ProfileResult) bool { return fmt.Sprint(a.Redirects) != fmt.Sprint(b.Redirects) }},
+		{"title", func(r models.ProfileResult) interface{} { return r.Title },
+			func(a, b models.ProfileResult) bool { return a.Title != b.Title }},
+		{"headings", func(r models.ProfileResult) interface{} { return len(r.Headings) },
+			func(a, b models.ProfileResult) bool {
+				change := setDiff(a.Headings, b.Headings)
+				return len(change.Added) > 0 || len(change.Removed) > 0
+			}},
+		{"internal_links", func(r models.ProfileResult) interface{} { return r.InternalLinks },
+			func(a, b models.ProfileResult) bool { return a.InternalLinks != b.InternalLinks }},
+		{"external_links", func(r models.ProfileResult) interface{} { return r.ExternalLinks },
+			func(a, b models.ProfileResult) bool { return a.ExternalLinks != b.ExternalLinks }},
+		{"content_length", func(r models.ProfileResult) interface{} { return r.ContentLength },
+			func(a, b models.ProfileResult) bool { return beyondTolerance(a.ContentLength, b.ContentLength) }},
+		{"word_count", func(r models.ProfileResult) interface{} { return r.WordCount },
+			func(a, b models.ProfileResult) bool { return beyondTolerance(a.WordCount, b.WordCount) }},
+		{"content_hash", func(r models.ProfileResult) interface{} { return r.ContentHash },
+			func(a, b models.ProfileResult) bool { return a.ContentHash != b.ContentHash }},
+		{"error", func(r models.ProfileResult) interface{} { return r.Error },
+			func(a, b models.ProfileResult) bool { return a.Error != b.Error }},
+	}
+	for _, field := range fields {
+		differs := false
+		values := map[string]interface{}{}
+		for _, r := range results {
+			values[r.Profile] = field.value(r)
+			if field.differ(*baseResult, r) {
+				differs = true
+			}
+		}
+		if differs {
+			comparison.Differences = append(comparison.Differences, ProfileDifference{Field: field.name, Values: values})
+		}
+	}
+	for _, r := range results {
+		if r.Profile != baseResult.Profile {
+			comparison.Headings[r.Profile] = setDiff(baseResult.Headings, r.Headings)
+		}
+	}
+	return comparison, nil
+}
+
+func beyondTolerance(a, b int) bool {
+	if a == b {
+		return false
+	}
+	return math.Abs(float64(a-b)) > profileTolerance*math.Max(float64(a), float64(b))
+}
@@ -0,0 +1,261 @@
+package crawl
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/shwetakhatra/url-analyzer/models"
+)
+
+// Subresource types
+const (
+	ResourceScript     = "script"
+	ResourceStylesheet = "stylesheet"
+	ResourceImage      = "image"
+	ResourceFont       = "font"
+	ResourceIframe     = "iframe"
+	ResourceMedia      = "media"
+	ResourceObject     = "object"
+	ResourceForm       = "form"
+)
+
+// resourceRef is a URL the page references from an element attribute
+type resourceRef struct {
+	Type      string
+	URL       *url.URL
+	Selection *goquery.Selection
+	Attribute string
+}
+
+// resourceAttrs lists which attribute of which elements loads what
+var resourceAttrs = []struct {
+	selector  string
+	attribute string
+	kind      string
+}{
+	{"script[src]", "src", ResourceScript},
+	{`link[rel~="stylesheet"][href]`, "href", ResourceStylesheet},
+	{`link[rel~="preload"][as="style"][href]`, "href", ResourceStylesheet},
+	{`link[rel~="preload"][as="script"][href]`, "href", ResourceScript},
+	{`link[rel~="preload"][as="font"][href]`, "href", ResourceFont},
+	{`link[rel~="icon"][href]`, "href", ResourceImage},
+	{"img[src]", "src", ResourceImage},
+	{"img[srcset]", "srcset", ResourceImage},
+	{"picture source[srcset]", "srcset", ResourceImage},
+	{"input[type=image][src]", "src", ResourceImage},
+	{"iframe[src]", "src", ResourceIframe},
+	{"frame[src]", "src", ResourceIframe},
+	{"video[src]", "src", ResourceMedia},
+	{"video[poster]", "poster", ResourceImage},
+	{"audio[src]", "src", ResourceMedia},
+	{"video source[src], audio source[src]", "src", ResourceMedia},
+	{"track[src]", "src", ResourceMedia},
+	{"object[data]", "data", ResourceObject},
+	{"embed[src]", "src", ResourceObject},
+	{"form[action]", "action", ResourceForm},
+}
+
+// documentBase returns the URL relative references of the page resolve
+// against, honouring a <base href> element
+func documentBase(doc *goquery.Document, pageURL *url.URL) *url.URL {
+	if href, ok := doc.Find("base[href]").First().Attr("href"); ok {
+		if base, err := pageURL.Parse(strings.TrimSpace(href)); err == nil {
+			return base
+		}
+	}
+	return pageURL
+}
+
+// subresourceRefs lists every subresource URL referenced by the page
+func subresourceRefs(doc *goquery.Document, base *url.URL) []resourceRef {
+	var refs []resourceRef
+	for _, ra := range resourceAttrs {
+		doc.Find(ra.selector).Each(func(_ int, s *goquery.Selection) {
+			value := s.AttrOr(ra.attribute, "")
+			candidates := []string{value}
+			if ra.attribute == "srcset" {
+				candidates = srcsetURLs(value)
+			}
+			for _, raw := range candidates {
+				raw = strings.TrimSpace(raw)
+				if raw == "" || strings.HasPrefix(raw, "data:") || strings.HasPrefix(raw, "javascript:") {
+					continue
+				}
+				ref, err := base.Parse(raw)
+				if err != nil || (ref.Scheme != "http" && ref.Scheme != "https") {
+					continue
+				}
+				refs = append(refs, resourceRef{Type: ra.kind, URL: ref, Selection: s, Attribute: ra.attribute})
+			}
+		})
+	}
+	return refs
+}
+
+// srcsetURLs extracts the image candidates of a srcset attribute
+func srcsetURLs(srcset string) []string {
+	var urls []string
+	for _, candidate := range strings.Split(srcset, ",") {
+		if fields := strings.Fields(candidate); len(fields) > 0 {
+			urls = append(urls, fields[0])
+		}
+	}
+	return urls
+}
+
+// resourceCheckConcurrency bounds the parallel requests of resource checks
+const resourceCheckConcurrency = 8
+
+// maxStylesheetSize caps how much of a stylesheet is read to find its fonts
+const maxStylesheetSize = 2 << 20
+
+var (
+	fontFaceBlock = regexp.MustCompile(`(?is)@font-face\s*\{[^}]*\}`)
+	cssURL        = regexp.MustCompile(`(?i)url\(\s*['"]?([^'")]+?)['"]?\s*\)`)
+)
+
+// buildInventory lists every subresource of the page and, when resource
+// checks are enabled, requests each of them to record status, size and
+// content type. The page weight is the size of the document plus that of all
+// checked resources.
+func buildInventory(doc *goquery.Document, pageURL *url.URL, pageSize int, opts CrawlOptions, urlID string, result *CrawlResult) {
+	base := documentBase(doc, pageURL)
+	seen := map[string]bool{}
+	add := func(kind string, ref *url.URL) {
+		link := ref.String()
+		if seen[link] {
+			return
+		}
+		seen[link] = true
+		result.Resources = append(result.Resources, models.Resource{URLID: urlID, Type: kind, Link: link})
+	}
+	for _, ref := range subresourceRefs(doc, base) {
+		if ref.Type != ResourceForm {
+			add(ref.Type, ref.URL)
+		}
+	}
+	doc.Find("style").Each(func(_ int, s *goquery.Selection) {
+		for _, font := range fontURLs(s.Text(), base) {
+			add(ResourceFont, font)
+		}
+	})
+
+	switch {
+	case opts.CheckResources && opts.History != nil:
+		for _, font := range opts.History.fonts() {
+			add(ResourceFont, font)
+		}
+		for i := range result.Resources {
+			opts.History.restoreResource(&result.Resources[i])
+		}
+	case opts.CheckResources:
+		// fonts declared by the stylesheets join the inventory and are
+		// checked in a second pass
+		for _, font := range checkResources(result.Resources) {
+			add(ResourceFont, font)
+		}
+		checkResources(result.Resources)
+	}
+
+	result.PageWeight = int64(pageSize)
+	result.RequestCount = 1 + len(result.Resources)
+	for _, res := range result.Resources {
+		result.PageWeight += res.Size
+		if res.Checked && (res.Status == 0 || res.Status >= 400) {
+			result.BrokenResourceCount++
+			result.BrokenLinks = append(result.BrokenLinks, models.BrokenLink{
+				URLID:  urlID,
+				Link:   res.Link,
+				Status: res.Status,
+				Type:   models.BrokenLinkTypeResource,
+			})
+		}
+	}
+}
+
+// checkResources requests the unchecked resources in parallel and returns
+// the font URLs declared by the stylesheets among them
+func checkResources(resources []models.Resource) []*url.URL {
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		fonts []*url.URL
+		sem   = make(chan struct{}, resourceCheckConcurrency)
+	)
+	for i := range resources {
+		if resources[i].Checked {
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(res *models.Resource) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			found := checkResource(res)
+			mu.Lock()
+			fonts = append(fonts, found...)
+			mu.Unlock()
+		}(&resources[i])
+	}
+	wg.Wait()
+	return fonts
+}
+
+func checkResource(res *models.Resource) []*url.URL {
+	res.Checked = true
+	if res.Type != ResourceStylesheet {
+		resp, err := linkClient.Head(res.Link)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusMethodNotAllowed && resp.StatusCode != http.StatusNotImplemented &&
+				resp.ContentLength >= 0 {
+				res.Status = resp.StatusCode
+				res.Size = resp.ContentLength
+				res.ContentType = resp.Header.Get("Content-Type")
+				return nil
+			}
+		}
+	}
+
+	// stylesheets, servers without HEAD support and responses without a
+	// Content-Length are downloaded to measure them
+	resp, err := linkClient.Get(res.Link)
+	if err != nil {
+		return nil
+	}
+	defer resp.Body.Close()
+	res.Status = resp.StatusCode
+	res.ContentType = resp.Header.Get("Content-Type")
+	if res.Type != ResourceStylesheet || resp.StatusCode >= 400 {
+		res.Size, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxBodySize))
+		return nil
+	}
+	css, _ := io.ReadAll(io.LimitReader(resp.Body, maxStylesheetSize))
+	res.Size = int64(len(css))
+	base, err := url.Parse(res.Link)
+	if err != nil {
+		return nil
+	}
+	return fontURLs(string(css), base)
+}
+
+// fontURLs returns the font files referenced by @font-face rules
+func fontURLs(css string, base *url.URL) []*url.URL {
+	var urls []*url.URL
+	for _, block := range fontFaceBlock.FindAllString(css, -1) {
+		for _, m := range cssURL.FindAllStringSubmatch(block, -1) {
+			if strings.HasPrefix(m[1], "data:") {
+				continue
+			}
+			if ref, err := base.Parse(m[1]); err == nil && (ref.Scheme == "http" || ref.Scheme == "https") {
+				urls = append(urls, ref)
+			}
+		}
+	}
+	return urls
+}
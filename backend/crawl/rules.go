@@ -0,0 +1,169 @@
+package crawl
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+	"github.com/shwetakhatra/url-analyzer/database"
+	"github.com/shwetakhatra/url-analyzer/models"
+)
+
+// ruleAnalyzer evaluates the rules of the user that apply to the URL
+type ruleAnalyzer struct{}
+
+func (ruleAnalyzer) Name() string        { return "rules" }
+func (ruleAnalyzer) Description() string { return "User-defined assertion rules" }
+func (ruleAnalyzer) HTMLOnly() bool      { return true }
+
+func (ruleAnalyzer) Analyze(in *AnalysisInput) (*AnalyzerOutput, error) {
+	return evaluateRules(in), nil
+}
+
+func init() {
+	Register(ruleAnalyzer{})
+}
+
+// ruleOperators compare the number of matched elements of a count rule
+// with the value of the rule
+var ruleOperators = map[string]func(count, value int) bool{
+	"==": func(count, value int) bool { return count == value },
+	"!=": func(count, value int) bool { return count != value },
+	"<":  func(count, value int) bool { return count < value },
+	"<=": func(count, value int) bool { return count <= value },
+	">":  func(count, value int) bool { return count > value },
+	">=": func(count, value int) bool { return count >= value },
+}
+
+// ValidateRule checks that a rule can be evaluated
+func ValidateRule(rule models.Rule) error {
+	if strings.TrimSpace(rule.Selector) == "" {
+		return errors.New("selector is required")
+	}
+	if _, err := cascadia.Compile(rule.Selector); err != nil {
+		return fmt.Errorf("invalid selector: %v", err)
+	}
+	switch rule.Assertion {
+	case models.RuleExists, models.RuleNotExists:
+	case models.RuleCount:
+		if _, ok := ruleOperators[rule.Operator]; !ok {
+			return errors.New("operator must be one of ==, !=, <, <=, >, >=")
+		}
+		if rule.Value < 0 {
+			return errors.New("value must not be negative")
+		}
+	default:
+		return errors.New("assertion must be exists, not_exists or count")
+	}
+	switch rule.Severity {
+	case models.SeverityError, models.SeverityWarning, models.SeverityNotice:
+	default:
+		return errors.New("severity must be error, warning or notice")
+	}
+	if rule.URLPattern == "" && len(rule.URLIDs) == 0 {
+		return errors.New("a rule needs a url_pattern or url_ids")
+	}
+	return nil
+}
+
+// RuleApplies tells whether a rule is attached to a URL, by id or pattern
+func RuleApplies(rule models.Rule, url models.URL) bool {
+	for _, id := range rule.URLIDs {
+		if id == url.ID {
+			return true
+		}
+	}
+	return rule.URLPattern != "" && patternRegexp(rule.URLPattern).MatchString(url.URL)
+}
+
+// patternRegexp turns a URL pattern into a regexp matching the whole URL
+func patternRegexp(pattern string) *regexp.Regexp {
+	parts := strings.Split(pattern, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	return regexp.MustCompile("^" + strings.Join(parts, ".*") + "$")
+}
+
+// rulesFor loads the enabled rules of the owner of a URL that apply to it
+func rulesFor(url models.URL) []models.Rule {
+	var rules []models.Rule
+	if err := database.DB.Where("user_id = ? AND enabled = ?", url.UserID, true).Order("created_at").Find(&rules).Error; err != nil {
+		debugLog("[DB] Error loading rules of user %s: %v", url.UserID, err)
+		return nil
+	}
+	var applicable []models.Rule
+	for _, rule := range rules {
+		if RuleApplies(rule, url) {
+			applicable = append(applicable, rule)
+		}
+	}
+	return applicable
+}
+
+// evaluateRules checks every rule against the document. Failed rules are
+// also reported as findings so they show up next to the built-in checks.
+func evaluateRules(in *AnalysisInput) *AnalyzerOutput {
+	out := &AnalyzerOutput{}
+	paths := newCSSPaths(in.Doc)
+	passed, failed := 0, 0
+	for _, rule := range in.Options.Rules {
+		res := models.RuleResult{URLID: in.URLID, RuleID: rule.ID, Name: rule.Name}
+		matcher, err := cascadia.Compile(rule.Selector)
+		if err != nil {
+			res.Message = fmt.Sprintf("invalid selector %q: %v", rule.Selector, err)
+		} else {
+			matches := in.Doc.FindMatcher(matcher)
+			res.Count = matches.Length()
+			res.Passed, res.Message = assertRule(rule, res.Count)
+			if !res.Passed {
+				finding := models.Finding{
+					Code:     "rule-" + rule.ID,
+					Severity: rule.Severity,
+					Message:  ruleLabel(rule) + ": " + res.Message,
+				}
+				if res.Count > 0 {
+					finding.Selector = paths.of(matches.First())
+					finding.Element = goquery.NodeName(matches.First())
+				}
+				out.Findings = append(out.Findings, finding)
+			}
+		}
+		if res.Passed {
+			passed++
+		} else {
+			failed++
+		}
+		in.Result.RuleResults = append(in.Result.RuleResults, res)
+	}
+	out.Metrics = map[string]interface{}{"passed": passed, "failed": failed}
+	return out
+}
+
+// assertRule decides a rule given the number of elements its selector
+// matched and describes the outcome
+func assertRule(rule models.Rule, count int) (bool, string) {
+	matched := fmt.Sprintf("%q matched %d element(s)", rule.Selector, count)
+	switch rule.Assertion {
+	case models.RuleExists:
+		return count > 0, matched + ", expected at least one"
+	case models.RuleNotExists:
+		return count == 0, matched + ", expected none"
+	case models.RuleCount:
+		if compare, ok := ruleOperators[rule.Operator]; ok {
+			return compare(count, rule.Value), fmt.Sprintf("%s, expected %s %d", matched, rule.Operator, rule.Value)
+		}
+		return false, "unknown operator " + rule.Operator
+	}
+	return false, "unknown assertion " + rule.Assertion
+}
+
+func ruleLabel(rule models.Rule) string {
+	if rule.Name != "" {
+		return rule.Name
+	}
+	return "rule"
+}
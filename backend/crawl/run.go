@@ -0,0 +1,189 @@
+package crawl
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/shwetakhatra/url-analyzer/database"
+	"github.com/shwetakhatra/url-analyzer/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// recordRun stores an analysis of a URL as its next version, along with what
+// changed since the previous successful run
+func recordRun(urlID, source, settings string, snapshot *models.Snapshot, result *CrawlResult, crawlErr error) {
+	run := models.Run{
+		URLID:    urlID,
+		Source:   source,
+		Status:   "done",
+		Settings: settings,
+	}
+	if snapshot != nil {
+		run.SnapshotID = snapshot.ID
+	}
+	if crawlErr != nil {
+		run.Status = "error"
+		run.Error = crawlErr.Error()
+	} else {
+		run.Summary = summarize(result)
+		run.MainText = result.MainText
+	}
+
+	if run.Status == "done" {
+		var previous models.Run
+		err := database.DB.Where("url_id = ? AND status = ?", urlID, "done").Order("version DESC").First(&previous).Error
+		if err == nil {
+			run.Changes = DiffRuns(previous, run).Summary()
+		}
+	}
+	if err := createRun(&run); err != nil {
+		debugLog("[DB] Error saving run for %s: %v", urlID, err)
+	}
+}
+
+// recordUnchangedRun stores a run for a crawl the server answered with 304
+// Not Modified. It repeats the summary of the last successful run, the one
+// it confirms, and refers to the latest snapshot, which holds the page the
+// server confirmed.
+func recordUnchangedRun(urlID, settings string) {
+	run := models.Run{
+		URLID:    urlID,
+		Source:   models.RunSourceFetch,
+		Status:   models.RunStatusUnchanged,
+		Settings: settings,
+	}
+	var previous models.Run
+	if err := database.DB.Where("url_id = ? AND status = ?", urlID, "done").Order("version DESC").First(&previous).Error; err == nil {
+		run.Summary = previous.Summary
+		run.MainText = previous.MainText
+		run.Changes = &models.ChangeSummary{ComparedTo: previous.Version, Fields: []string{}}
+	}
+	var snapshot models.Snapshot
+	if err := database.DB.Where("url_id = ?", urlID).Order("fetched_at DESC").First(&snapshot).Error; err == nil {
+		run.SnapshotID = snapshot.ID
+	}
+	if err := createRun(&run); err != nil {
+		debugLog("[DB] Error saving run for %s: %v", urlID, err)
+	}
+}
+
+// analysisSettings fingerprints what besides the page goes into a crawl of
+// a URL. The encrypted request options and login recipe stand in for their
+// content, so that no secret is hashed.
+func analysisSettings(url models.URL, opts CrawlOptions) string {
+	data, err := json.Marshal(struct {
+		CheckResources bool
+		Disabled       map[string]bool
+		Rules          []models.Rule
+		Extractors     []models.Extractor
+		Profiles       []string
+		RequestOptions string
+		LoginRecipe    string
+	}{opts.CheckResources, opts.Disabled, opts.Rules, opts.Extractors, url.Profiles, url.RequestOptions, url.LoginRecipe})
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// settingsChanged tells whether the latest run of a URL was analyzed with
+// other settings, in which case an unchanged page still needs analyzing
+func settingsChanged(urlID, settings string) bool {
+	var last models.Run
+	if err := database.DB.Select("settings").Where("url_id = ?", urlID).Order("version DESC").First(&last).Error; err != nil {
+		return true
+	}
+	return last.Settings != settings
+}
+
+// createRun stores a run as the next version of its URL. The versions of
+// the URL stay locked until the run is stored, and the unique index on the
+// URL and version rejects a duplicate all the same.
+func createRun(run *models.Run) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		var last int
+		err := tx.Model(&models.Run{}).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("url_id = ?", run.URLID).
+			Select("COALESCE(MAX(version), 0)").
+			Scan(&last).Error
+		if err != nil {
+			return err
+		}
+		run.Version = last + 1
+		return tx.Create(run).Error
+	})
+}
+
+// summarize condenses a result into what runs are compared by
+func summarize(result *CrawlResult) models.RunSummary {
+	summary := models.RunSummary{
+		Title:           result.Title,
+		MetaDescription: result.MetaDescription,
+		CanonicalURL:    result.CanonicalURL,
+		RobotsMeta:      result.RobotsMeta,
+		Lang:            result.Lang,
+		HTMLVersion:     result.HTMLVersion,
+		DocumentKind:    result.DocumentKind,
+		HeadingCounts:   [6]int{result.H1Count, result.H2Count, result.H3Count, result.H4Count, result.H5Count, result.H6Count},
+		InternalLinks:   result.InternalLinks,
+		ExternalLinks:   result.ExternalLinks,
+		BrokenLinks:     result.BrokenLinkCount,
+		WordCount:       result.WordCount,
+		ContentHash:     result.ContentHash,
+		SEOScore:        result.SEOScore,
+		SecurityScore:   result.SecurityScore,
+		SecurityGrade:   result.SecurityGrade,
+		PageWeight:      result.PageWeight,
+		ThirdPartyCount: result.ThirdPartyCount,
+		TrackerCount:    result.TrackerCount,
+		Findings:        []models.RunFinding{},
+		Rules:           []models.RunRule{},
+		Extracted:       map[string][]string{},
+	}
+	if result.Page != nil {
+		summary.StatusCode = result.Page.StatusCode
+	}
+	summary.Metrics = map[string]map[string]interface{}{}
+	for _, analyzer := range result.AnalyzerResults {
+		if analyzer.Metrics != nil {
+			summary.Metrics[analyzer.Analyzer] = analyzer.Metrics
+		}
+	}
+	for _, heading := range result.Headings {
+		summary.Headings = append(summary.Headings, fmt.Sprintf("h%d %s", heading.Level, heading.Text))
+	}
+	summary.Links = append([]string{}, result.Links...)
+	summary.BrokenLinkURLs = []string{}
+	for _, link := range result.BrokenLinks {
+		if link.Type == models.BrokenLinkTypeLink {
+			summary.BrokenLinkURLs = append(summary.BrokenLinkURLs, link.Link)
+		}
+	}
+	for _, rule := range result.RuleResults {
+		summary.Rules = append(summary.Rules, models.RunRule{
+			RuleID:  rule.RuleID,
+			Name:    rule.Name,
+			Passed:  rule.Passed,
+			Count:   rule.Count,
+			Message: rule.Message,
+		})
+	}
+	for _, value := range result.ExtractedValues {
+		summary.Extracted[value.Name] = value.Values
+	}
+	for _, finding := range result.Findings {
+		summary.Findings = append(summary.Findings, models.RunFinding{
+			Category: finding.Category,
+			Code:     finding.Code,
+			Severity: finding.Severity,
+			Selector: finding.Selector,
+			Message:  finding.Message,
+		})
+	}
+	return summary
+}
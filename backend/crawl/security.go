@@ -0,0 +1,180 @@
+package crawl
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/shwetakhatra/url-analyzer/models"
+)
+
+// CategorySecurity groups the findings about security headers and cookies
+const CategorySecurity = "security"
+
+// hstsMinMaxAge is the shortest HSTS max-age considered adequate (180 days)
+const hstsMinMaxAge = 180 * 24 * 60 * 60
+
+// securityHeaders are the response headers recorded for every page
+var securityHeaders = []string{
+	"Content-Security-Policy",
+	"Strict-Transport-Security",
+	"X-Frame-Options",
+	"X-Content-Type-Options",
+	"Referrer-Policy",
+	"Permissions-Policy",
+}
+
+var hstsMaxAge = regexp.MustCompile(`(?i)max-age\s*=\s*"?(\d+)"?`)
+
+// analyzeSecurity grades the security response headers and the flags of
+// every cookie the page sets
+func analyzeSecurity(header http.Header, cookies []*http.Cookie, https bool, urlID string, result *CrawlResult) {
+	result.SecurityHeaders = map[string]string{}
+	for _, name := range securityHeaders {
+		if value := strings.Join(header.Values(name), ", "); value != "" {
+			result.SecurityHeaders[name] = value
+		}
+	}
+	result.MissingSecurityHeaders = []string{}
+
+	var findings []models.Finding
+	add := func(code, severity, message string) {
+		findings = append(findings, models.Finding{
+			URLID:    urlID,
+			Category: CategorySecurity,
+			Code:     code,
+			Severity: severity,
+			Message:  message,
+		})
+	}
+	missing := func(name, severity string) {
+		result.MissingSecurityHeaders = append(result.MissingSecurityHeaders, name)
+		add("missing_"+strings.ReplaceAll(strings.ToLower(name), "-", "_"), severity, name+" header is missing")
+	}
+
+	csp := strings.ToLower(result.SecurityHeaders["Content-Security-Policy"])
+	if csp == "" {
+		missing("Content-Security-Policy", models.SeverityError)
+		if header.Get("Content-Security-Policy-Report-Only") != "" {
+			add("csp_report_only", models.SeverityNotice, "Content-Security-Policy is only deployed in report-only mode")
+		}
+	} else {
+		scriptSrc := cspDirective(csp, "script-src")
+		if scriptSrc == "" {
+			scriptSrc = cspDirective(csp, "default-src")
+		}
+		if strings.Contains(scriptSrc, "'unsafe-inline'") &&
+			!strings.Contains(scriptSrc, "'nonce-") && !strings.Contains(scriptSrc, "'sha") {
+			add("csp_unsafe_inline", models.SeverityWarning, "Content-Security-Policy allows inline scripts")
+		}
+		if strings.Contains(scriptSrc, "'unsafe-eval'") {
+			add("csp_unsafe_eval", models.SeverityWarning, "Content-Security-Policy allows eval()")
+		}
+	}
+
+	if https {
+		hsts := result.SecurityHeaders["Strict-Transport-Security"]
+		if hsts == "" {
+			missing("Strict-Transport-Security", models.SeverityError)
+		} else {
+			maxAge := 0
+			if m := hstsMaxAge.FindStringSubmatch(hsts); m != nil {
+				maxAge, _ = strconv.Atoi(m[1])
+			}
+			if maxAge < hstsMinMaxAge {
+				add("hsts_short_max_age", models.SeverityWarning,
+					fmt.Sprintf("HSTS max-age is %d seconds, at least %d is recommended", maxAge, hstsMinMaxAge))
+			}
+			if !strings.Contains(strings.ToLower(hsts), "includesubdomains") {
+				add("hsts_no_subdomains", models.SeverityNotice, "HSTS does not cover subdomains")
+			}
+		}
+	}
+
+	frameOptions := strings.ToUpper(strings.TrimSpace(result.SecurityHeaders["X-Frame-Options"]))
+	switch {
+	case frameOptions == "" && cspDirective(csp, "frame-ancestors") == "":
+		missing("X-Frame-Options", models.SeverityWarning)
+	case frameOptions != "" && frameOptions != "DENY" && frameOptions != "SAMEORIGIN":
+		add("invalid_x_frame_options", models.SeverityWarning,
+			fmt.Sprintf("X-Frame-Options value %q is not DENY or SAMEORIGIN", frameOptions))
+	}
+
+	switch contentTypeOptions := strings.ToLower(strings.TrimSpace(result.SecurityHeaders["X-Content-Type-Options"])); {
+	case contentTypeOptions == "":
+		missing("X-Content-Type-Options", models.SeverityWarning)
+	case contentTypeOptions != "nosniff":
+		add("invalid_x_content_type_options", models.SeverityWarning,
+			fmt.Sprintf("X-Content-Type-Options value %q is not nosniff", contentTypeOptions))
+	}
+
+	switch referrerPolicy := strings.ToLower(result.SecurityHeaders["Referrer-Policy"]); {
+	case referrerPolicy == "":
+		missing("Referrer-Policy", models.SeverityNotice)
+	case strings.Contains(referrerPolicy, "unsafe-url") || strings.Contains(referrerPolicy, "no-referrer-when-downgrade"):
+		add("weak_referrer_policy", models.SeverityWarning,
+			fmt.Sprintf("Referrer-Policy %q leaks full URLs to other sites", referrerPolicy))
+	}
+
+	if result.SecurityHeaders["Permissions-Policy"] == "" {
+		missing("Permissions-Policy", models.SeverityNotice)
+	}
+
+	for _, cookie := range cookies {
+		if !cookie.Secure && https {
+			add("cookie_not_secure", models.SeverityWarning,
+				fmt.Sprintf("Cookie %q is set without the Secure flag", cookie.Name))
+		}
+		if !cookie.HttpOnly {
+			add("cookie_not_httponly", models.SeverityWarning,
+				fmt.Sprintf("Cookie %q is set without the HttpOnly flag", cookie.Name))
+		}
+		switch cookie.SameSite {
+		case 0, http.SameSiteDefaultMode:
+			// 0 means the attribute is absent, the default mode an unknown value
+			add("cookie_no_samesite", models.SeverityNotice,
+				fmt.Sprintf("Cookie %q is set without a SameSite attribute", cookie.Name))
+		case http.SameSiteNoneMode:
+			if !cookie.Secure {
+				add("cookie_samesite_none_insecure", models.SeverityError,
+					fmt.Sprintf("Cookie %q uses SameSite=None without the Secure flag", cookie.Name))
+			}
+		}
+	}
+
+	result.SecurityScore = score(findings)
+	result.SecurityGrade = grade(result.SecurityScore)
+	result.Findings = append(result.Findings, findings...)
+}
+
+// cspDirective returns the source list of a CSP directive, or "" when the
+// policy does not contain it
+func cspDirective(policy, name string) string {
+	for _, directive := range strings.Split(policy, ";") {
+		fields := strings.Fields(directive)
+		if len(fields) > 0 && fields[0] == name {
+			if len(fields) == 1 {
+				return "'none'"
+			}
+			return strings.Join(fields[1:], " ")
+		}
+	}
+	return ""
+}
+
+// grade maps a 0–100 score to a school grade
+func grade(score int) string {
+	switch {
+	case score >= 90:
+		return "A"
+	case score >= 80:
+		return "B"
+	case score >= 70:
+		return "C"
+	case score >= 60:
+		return "D"
+	}
+	return "F"
+}
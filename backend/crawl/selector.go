@@ -0,0 +1,91 @@
+package crawl
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+var plainID = regexp.MustCompile(`^[A-Za-z][\w-]*$`)
+
+// cssPaths builds CSS selectors for the elements of one document. The ids
+// of the document are counted once, when the first selector needs them.
+type cssPaths struct {
+	doc *goquery.Document
+	ids map[string]int
+}
+
+func newCSSPaths(doc *goquery.Document) *cssPaths {
+	return &cssPaths{doc: doc}
+}
+
+// of builds a CSS selector that points at the first node of s, anchored at
+// the closest ancestor with a usable id or at the html element.
+func (p *cssPaths) of(s *goquery.Selection) string {
+	if s.Length() == 0 {
+		return ""
+	}
+	var parts []string
+	for n := s.Get(0); n != nil && n.Type == html.ElementNode; n = n.Parent {
+		if id := attrValue(n, "id"); plainID.MatchString(id) && p.idCount(id) == 1 {
+			parts = append(parts, n.Data+"#"+id)
+			break
+		}
+		part := n.Data
+		if index, total := typeIndex(n); total > 1 {
+			part += fmt.Sprintf(":nth-of-type(%d)", index)
+		}
+		parts = append(parts, part)
+	}
+	for i, j := 0, len(parts)-1; i < j; i, j = i+1, j-1 {
+		parts[i], parts[j] = parts[j], parts[i]
+	}
+	return strings.Join(parts, " > ")
+}
+
+// typeIndex returns the 1-based position of n among its siblings of the same
+// element type, and how many such siblings there are
+func typeIndex(n *html.Node) (int, int) {
+	if n.Parent == nil {
+		return 1, 1
+	}
+	index, total := 0, 0
+	for sib := n.Parent.FirstChild; sib != nil; sib = sib.NextSibling {
+		if sib.Type != html.ElementNode || sib.Data != n.Data {
+			continue
+		}
+		total++
+		if sib == n {
+			index = total
+		}
+	}
+	return index, total
+}
+
+func attr(n *html.Node, key string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+func attrValue(n *html.Node, key string) string {
+	value, _ := attr(n, key)
+	return value
+}
+
+// idCount counts the elements of the document that carry the given id
+func (p *cssPaths) idCount(id string) int {
+	if p.ids == nil {
+		p.ids = map[string]int{}
+		p.doc.Find("[id]").Each(func(_ int, s *goquery.Selection) {
+			p.ids[s.AttrOr("id", "")]++
+		})
+	}
+	return p.ids[id]
+}
@@ -0,0 +1,188 @@
+package crawl
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/shwetakhatra/url-analyzer/models"
+)
+
+// CategorySEO groups the findings of the SEO audit
+const CategorySEO = "seo"
+
+// recommended lengths in characters, as used by the common search engines
+const (
+	titleMinLength       = 30
+	titleMaxLength       = 60
+	descriptionMinLength = 70
+	descriptionMaxLength = 160
+)
+
+// severityPenalty is what a finding of each severity costs on a 100 point score
+var severityPenalty = map[string]int{
+	models.SeverityError:   15,
+	models.SeverityWarning: 5,
+	models.SeverityNotice:  2,
+}
+
+var requiredOpenGraph = []string{"og:title", "og:description", "og:image", "og:url"}
+
+// analyzeSEO extracts the SEO relevant metadata of the page and audits it
+func analyzeSEO(doc *goquery.Document, header http.Header, pageURL *url.URL, urlID string, result *CrawlResult) {
+	result.MetaDescription = strings.TrimSpace(metaContent(doc, "name", "description"))
+	result.RobotsMeta = strings.TrimSpace(metaContent(doc, "name", "robots"))
+	result.XRobotsTag = strings.Join(header.Values("X-Robots-Tag"), ", ")
+	result.Viewport = strings.TrimSpace(metaContent(doc, "name", "viewport"))
+	result.Lang = strings.TrimSpace(doc.Find("html").AttrOr("lang", ""))
+	result.TitleLength = utf8.RuneCountInString(strings.TrimSpace(result.Title))
+	result.DescriptionLength = utf8.RuneCountInString(result.MetaDescription)
+	result.OpenGraph = metaPrefixed(doc, "property", "og:")
+	result.TwitterCard = metaPrefixed(doc, "name", "twitter:")
+
+	canonicals := doc.Find(`link[rel~="canonical"][href]`)
+	if canonicals.Length() > 0 {
+		href := strings.TrimSpace(canonicals.First().AttrOr("href", ""))
+		if ref, err := pageURL.Parse(href); err == nil {
+			result.CanonicalURL = ref.String()
+		} else {
+			result.CanonicalURL = href
+		}
+	}
+
+	var findings []models.Finding
+	add := func(code, severity, message string) {
+		findings = append(findings, models.Finding{
+			URLID:    urlID,
+			Category: CategorySEO,
+			Code:     code,
+			Severity: severity,
+			Message:  message,
+		})
+	}
+
+	switch {
+	case result.TitleLength == 0:
+		add("missing_title", models.SeverityError, "Page has no title")
+	case result.TitleLength < titleMinLength:
+		add("title_too_short", models.SeverityWarning,
+			fmt.Sprintf("Title is %d characters, recommended minimum is %d", result.TitleLength, titleMinLength))
+	case result.TitleLength > titleMaxLength:
+		add("title_too_long", models.SeverityWarning,
+			fmt.Sprintf("Title is %d characters, recommended maximum is %d", result.TitleLength, titleMaxLength))
+	}
+
+	switch {
+	case result.DescriptionLength == 0:
+		add("missing_description", models.SeverityError, "Page has no meta description")
+	case result.DescriptionLength < descriptionMinLength:
+		add("description_too_short", models.SeverityWarning,
+			fmt.Sprintf("Meta description is %d characters, recommended minimum is %d", result.DescriptionLength, descriptionMinLength))
+	case result.DescriptionLength > descriptionMaxLength:
+		add("description_too_long", models.SeverityWarning,
+			fmt.Sprintf("Meta description is %d characters, recommended maximum is %d", result.DescriptionLength, descriptionMaxLength))
+	}
+
+	switch {
+	case canonicals.Length() == 0:
+		add("missing_canonical", models.SeverityNotice, "Page has no canonical link")
+	case canonicals.Length() > 1:
+		add("multiple_canonicals", models.SeverityWarning,
+			fmt.Sprintf("Page declares %d canonical links", canonicals.Length()))
+	}
+	if canonical, err := url.Parse(result.CanonicalURL); err == nil && result.CanonicalURL != "" &&
+		!strings.EqualFold(canonical.Hostname(), pageURL.Hostname()) {
+		add("canonical_cross_domain", models.SeverityNotice, "Canonical link points to "+canonical.Hostname())
+	}
+
+	robots := robotsDirectives(result.RobotsMeta + "," + result.XRobotsTag)
+	if robots["noindex"] || robots["none"] {
+		add("noindex", models.SeverityWarning, "Page is excluded from indexing by robots directives")
+	}
+	if robots["nofollow"] || robots["none"] {
+		add("nofollow", models.SeverityNotice, "Links on the page are not followed by robots directives")
+	}
+
+	if result.Viewport == "" {
+		add("missing_viewport", models.SeverityWarning, "Page has no viewport meta tag")
+	}
+
+	var missingOG []string
+	for _, property := range requiredOpenGraph {
+		if result.OpenGraph[property] == "" {
+			missingOG = append(missingOG, property)
+		}
+	}
+	if len(missingOG) > 0 {
+		add("missing_open_graph", models.SeverityNotice, "Missing Open Graph tags: "+strings.Join(missingOG, ", "))
+	}
+	if result.TwitterCard["twitter:card"] == "" {
+		add("missing_twitter_card", models.SeverityNotice, "Page has no twitter:card meta tag")
+	}
+
+	result.SEOScore = score(findings)
+	result.Findings = append(result.Findings, findings...)
+}
+
+// score turns a set of findings into a 0–100 score
+func score(findings []models.Finding) int {
+	total := 100
+	for _, f := range findings {
+		total -= severityPenalty[f.Severity]
+	}
+	if total < 0 {
+		return 0
+	}
+	return total
+}
+
+// robotsDirectives splits robots meta and X-Robots-Tag values into a set of
+// directives, dropping user agent prefixes such as "googlebot: noindex"
+func robotsDirectives(value string) map[string]bool {
+	directives := map[string]bool{}
+	for _, part := range strings.Split(strings.ToLower(value), ",") {
+		part = strings.TrimSpace(part)
+		if i := strings.Index(part, ":"); i >= 0 && !strings.Contains(part[:i], "-") {
+			part = strings.TrimSpace(part[i+1:])
+		}
+		if part != "" {
+			directives[part] = true
+		}
+	}
+	return directives
+}
+
+func metaContent(doc *goquery.Document, attr, name string) string {
+	var content string
+	doc.Find("meta[" + attr + "]").EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		if strings.EqualFold(strings.TrimSpace(s.AttrOr(attr, "")), name) {
+			content = s.AttrOr("content", "")
+			return false
+		}
+		return true
+	})
+	return content
+}
+
+// metaPrefixed collects meta tags such as og:* or twitter:* into a map,
+// keeping the first value of repeated properties
+func metaPrefixed(doc *goquery.Document, attr, prefix string) map[string]string {
+	tags := map[string]string{}
+	doc.Find("meta[content]").Each(func(_ int, s *goquery.Selection) {
+		key := strings.ToLower(strings.TrimSpace(s.AttrOr(attr, "")))
+		if key == "" && attr == "name" {
+			// twitter tags are often published with property instead of name
+			key = strings.ToLower(strings.TrimSpace(s.AttrOr("property", "")))
+		}
+		if !strings.HasPrefix(key, prefix) {
+			return
+		}
+		if _, seen := tags[key]; !seen {
+			tags[key] = strings.TrimSpace(s.AttrOr("content", ""))
+		}
+	})
+	return tags
+}
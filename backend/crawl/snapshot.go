@@ -0,0 +1,147 @@
+package crawl
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"io"
+	"net/url"
+
+	"github.com/google/uuid"
+	"github.com/shwetakhatra/url-analyzer/database"
+	"github.com/shwetakhatra/url-analyzer/models"
+	"github.com/shwetakhatra/url-analyzer/storage"
+)
+
+// snapshotRetention is how many snapshots are kept per URL; older ones are
+// pruned together with their blobs
+const snapshotRetention = 20
+
+// saveSnapshot archives the body of a fetched page gzipped in blob storage
+// and records it with the response headers
+func saveSnapshot(urlID string, page *Page) (*models.Snapshot, error) {
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	if _, err := zw.Write(page.Body); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(page.Body)
+	snapshot := &models.Snapshot{
+		ID:             uuid.New().String(),
+		URLID:          urlID,
+		FinalURL:       page.URL.String(),
+		StatusCode:     page.StatusCode,
+		Headers:        page.Header,
+		ContentType:    page.Header.Get("Content-Type"),
+		Size:           len(page.Body),
+		CompressedSize: compressed.Len(),
+		SHA256:         hex.EncodeToString(sum[:]),
+		FetchedAt:      page.FetchedAt,
+	}
+	snapshot.BlobKey = "snapshots/" + urlID + "/" + snapshot.ID + ".gz"
+	if page.TLS != nil {
+		snapshot.TLSVersion = page.TLS.Version
+		for _, cert := range page.TLS.PeerCertificates {
+			snapshot.PeerCertificates = append(snapshot.PeerCertificates, cert.Raw)
+		}
+	}
+
+	ctx := context.Background()
+	if err := storage.Blobs.Put(ctx, snapshot.BlobKey, compressed.Bytes(), "application/gzip"); err != nil {
+		return nil, err
+	}
+	if err := database.DB.Create(snapshot).Error; err != nil {
+		storage.Blobs.Delete(ctx, snapshot.BlobKey)
+		return nil, err
+	}
+	pruneSnapshots(urlID)
+	return snapshot, nil
+}
+
+// pruneSnapshots drops the snapshots of a URL beyond the retention limit
+func pruneSnapshots(urlID string) {
+	var keep []string
+	if err := database.DB.Model(&models.Snapshot{}).
+		Where("url_id = ?", urlID).
+		Order("fetched_at DESC").
+		Limit(snapshotRetention).
+		Pluck("id", &keep).Error; err != nil {
+		debugLog("[DB] Error listing snapshots of %s: %v", urlID, err)
+		return
+	}
+	if len(keep) < snapshotRetention {
+		return
+	}
+	var old []models.Snapshot
+	if err := database.DB.Where("url_id = ? AND id NOT IN ?", urlID, keep).Find(&old).Error; err != nil {
+		debugLog("[DB] Error listing old snapshots of %s: %v", urlID, err)
+		return
+	}
+	DeleteSnapshots(old)
+}
+
+// DeleteSnapshots removes snapshots and their blobs
+func DeleteSnapshots(snapshots []models.Snapshot) {
+	for _, snapshot := range snapshots {
+		if err := storage.Blobs.Delete(context.Background(), snapshot.BlobKey); err != nil {
+			debugLog("[Storage] Error deleting snapshot %s: %v", snapshot.BlobKey, err)
+			continue
+		}
+		if err := database.DB.Delete(&snapshot).Error; err != nil {
+			debugLog("[DB] Error deleting snapshot %s: %v", snapshot.ID, err)
+		}
+	}
+}
+
+// SnapshotBody returns the uncompressed body of a snapshot
+func SnapshotBody(ctx context.Context, snapshot models.Snapshot) ([]byte, error) {
+	compressed, err := storage.Blobs.Get(ctx, snapshot.BlobKey)
+	if err != nil {
+		return nil, err
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
+
+// LoadSnapshot restores the page a snapshot was taken of, so that it can be
+// analyzed again without fetching it
+func LoadSnapshot(ctx context.Context, snapshot models.Snapshot) (*Page, error) {
+	body, err := SnapshotBody(ctx, snapshot)
+	if err != nil {
+		return nil, err
+	}
+	pageURL, err := url.Parse(snapshot.FinalURL)
+	if err != nil {
+		return nil, err
+	}
+	page := &Page{
+		URL:        pageURL,
+		StatusCode: snapshot.StatusCode,
+		Header:     snapshot.Headers,
+		Body:       body,
+		FetchedAt:  snapshot.FetchedAt,
+	}
+	if len(snapshot.PeerCertificates) > 0 {
+		page.TLS = &tls.ConnectionState{Version: snapshot.TLSVersion}
+		for _, der := range snapshot.PeerCertificates {
+			cert, err := x509.ParseCertificate(der)
+			if err != nil {
+				return nil, err
+			}
+			page.TLS.PeerCertificates = append(page.TLS.PeerCertificates, cert)
+		}
+	}
+	return page, nil
+}
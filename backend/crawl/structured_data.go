@@ -0,0 +1,344 @@
+package crawl
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/shwetakhatra/url-analyzer/models"
+	"golang.org/x/net/html"
+)
+
+// Structured data formats
+const (
+	FormatJSONLD    = "json-ld"
+	FormatMicrodata = "microdata"
+	FormatRDFa      = "rdfa"
+)
+
+// requiredProperties lists, per schema.org type, the properties an item needs.
+// Each entry is a set of alternatives of which at least one must be present.
+var requiredProperties = map[string][][]string{
+	"Product":        {{"name"}, {"offers", "review", "aggregateRating"}},
+	"Offer":          {{"price", "priceSpecification"}, {"priceCurrency", "priceSpecification"}},
+	"Article":        {{"headline"}, {"author"}, {"datePublished"}},
+	"NewsArticle":    {{"headline"}, {"author"}, {"datePublished"}},
+	"BlogPosting":    {{"headline"}, {"author"}, {"datePublished"}},
+	"Organization":   {{"name"}, {"url"}},
+	"LocalBusiness":  {{"name"}, {"address"}},
+	"Person":         {{"name"}},
+	"WebSite":        {{"name"}, {"url"}},
+	"Event":          {{"name"}, {"startDate"}, {"location"}},
+	"Recipe":         {{"name"}, {"image"}},
+	"Review":         {{"itemReviewed"}, {"author"}},
+	"BreadcrumbList": {{"itemListElement"}},
+	"FAQPage":        {{"mainEntity"}},
+	"JobPosting":     {{"title"}, {"datePosted"}, {"description"}, {"hiringOrganization"}},
+}
+
+// extractStructuredData collects JSON-LD, microdata and RDFa items and
+// validates them against the required schema.org properties
+func extractStructuredData(doc *goquery.Document, urlID string, result *CrawlResult) {
+	var items []models.StructuredData
+	doc.Find(`script[type="application/ld+json"]`).Each(func(_ int, s *goquery.Selection) {
+		items = append(items, parseJSONLD(s.Text())...)
+	})
+	doc.Find("[itemscope]").Each(func(_ int, s *goquery.Selection) {
+		if _, nested := s.Attr("itemprop"); nested && s.ParentsFiltered("[itemscope]").Length() > 0 {
+			return
+		}
+		items = append(items, newStructuredData(FormatMicrodata, microdataItem(s.Get(0))))
+	})
+	doc.Find("[typeof]").Each(func(_ int, s *goquery.Selection) {
+		if _, nested := s.Attr("property"); nested && s.ParentsFiltered("[typeof]").Length() > 0 {
+			return
+		}
+		items = append(items, newStructuredData(FormatRDFa, rdfaItem(s.Get(0))))
+	})
+
+	for i := range items {
+		items[i].URLID = urlID
+	}
+	result.StructuredData = items
+}
+
+func parseJSONLD(text string) []models.StructuredData {
+	var parsed interface{}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(text)), &parsed); err != nil {
+		return []models.StructuredData{{
+			Format: FormatJSONLD,
+			Errors: []string{"invalid JSON: " + err.Error()},
+		}}
+	}
+	var items []models.StructuredData
+	var collect func(value interface{})
+	collect = func(value interface{}) {
+		switch v := value.(type) {
+		case []interface{}:
+			for _, entry := range v {
+				collect(entry)
+			}
+		case map[string]interface{}:
+			if graph, ok := v["@graph"]; ok {
+				collect(graph)
+				return
+			}
+			items = append(items, newStructuredData(FormatJSONLD, v))
+		}
+	}
+	collect(parsed)
+	if len(items) == 0 {
+		return []models.StructuredData{{
+			Format: FormatJSONLD,
+			Errors: []string{"JSON-LD block contains no objects"},
+		}}
+	}
+	return items
+}
+
+func newStructuredData(format string, data map[string]interface{}) models.StructuredData {
+	item := models.StructuredData{
+		Format: format,
+		Type:   strings.Join(itemTypes(data["@type"]), ","),
+		Data:   data,
+	}
+	item.Errors = validateItem(data)
+	item.Valid = len(item.Errors) == 0
+	return item
+}
+
+func validateItem(data map[string]interface{}) []string {
+	errors := []string{}
+	types := itemTypes(data["@type"])
+	if len(types) == 0 {
+		return append(errors, "item has no type")
+	}
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	present := map[string]bool{}
+	for _, key := range keys {
+		value := data[key]
+		if isEmptyValue(value) {
+			continue
+		}
+		present[shortName(key)] = true
+		// nested typed items such as an Offer inside a Product are checked too
+		for _, nested := range nestedItems(value) {
+			if len(itemTypes(nested["@type"])) > 0 {
+				for _, err := range validateItem(nested) {
+					errors = append(errors, shortName(key)+": "+err)
+				}
+			}
+		}
+	}
+	for _, t := range types {
+		for _, alternatives := range requiredProperties[t] {
+			found := false
+			for _, property := range alternatives {
+				if present[property] {
+					found = true
+					break
+				}
+			}
+			if !found {
+				errors = append(errors, fmt.Sprintf("%s is missing required property %s", t, strings.Join(alternatives, " or ")))
+			}
+		}
+	}
+	return errors
+}
+
+// itemTypes normalizes "@type" values such as "https://schema.org/Product"
+// or ["schema:Article"] to plain type names
+func itemTypes(value interface{}) []string {
+	var raw []string
+	switch v := value.(type) {
+	case string:
+		raw = strings.Fields(v)
+	case []interface{}:
+		for _, entry := range v {
+			if s, ok := entry.(string); ok {
+				raw = append(raw, s)
+			}
+		}
+	}
+	types := make([]string, 0, len(raw))
+	for _, t := range raw {
+		if t = shortName(t); t != "" {
+			types = append(types, t)
+		}
+	}
+	sort.Strings(types)
+	return types
+}
+
+func shortName(name string) string {
+	if i := strings.LastIndexAny(name, "/#:"); i >= 0 && !strings.HasPrefix(name, "@") {
+		return name[i+1:]
+	}
+	return name
+}
+
+func nestedItems(value interface{}) []map[string]interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return []map[string]interface{}{v}
+	case []interface{}:
+		var items []map[string]interface{}
+		for _, entry := range v {
+			if m, ok := entry.(map[string]interface{}); ok {
+				items = append(items, m)
+			}
+		}
+		return items
+	}
+	return nil
+}
+
+func isEmptyValue(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return strings.TrimSpace(v) == ""
+	case []interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+// microdataItem reads the properties of an itemscope element
+func microdataItem(n *html.Node) map[string]interface{} {
+	item := map[string]interface{}{}
+	if itemType := attrValue(n, "itemtype"); itemType != "" {
+		item["@type"] = itemType
+	}
+	if id := attrValue(n, "itemid"); id != "" {
+		item["@id"] = id
+	}
+	var walk func(*html.Node)
+	walk = func(parent *html.Node) {
+		for c := parent.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			_, scoped := attr(c, "itemscope")
+			if props := strings.Fields(attrValue(c, "itemprop")); len(props) > 0 {
+				var value interface{}
+				if scoped {
+					value = microdataItem(c)
+				} else {
+					value = microdataValue(c)
+				}
+				for _, prop := range props {
+					addProperty(item, prop, value)
+				}
+			}
+			if !scoped {
+				walk(c)
+			}
+		}
+	}
+	walk(n)
+	return item
+}
+
+func microdataValue(n *html.Node) interface{} {
+	switch n.Data {
+	case "meta":
+		return attrValue(n, "content")
+	case "a", "area", "link":
+		return attrValue(n, "href")
+	case "audio", "embed", "iframe", "img", "source", "track", "video":
+		return attrValue(n, "src")
+	case "object":
+		return attrValue(n, "data")
+	case "data", "meter":
+		return attrValue(n, "value")
+	case "time":
+		if datetime, ok := attr(n, "datetime"); ok {
+			return datetime
+		}
+	}
+	return nodeText(n)
+}
+
+// rdfaItem reads the properties of a typeof element
+func rdfaItem(n *html.Node) map[string]interface{} {
+	item := map[string]interface{}{"@type": attrValue(n, "typeof")}
+	if vocab := attrValue(n, "vocab"); vocab != "" {
+		item["@context"] = vocab
+	}
+	if resource := attrValue(n, "resource"); resource != "" {
+		item["@id"] = resource
+	}
+	var walk func(*html.Node)
+	walk = func(parent *html.Node) {
+		for c := parent.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			_, typed := attr(c, "typeof")
+			if props := strings.Fields(attrValue(c, "property")); len(props) > 0 {
+				var value interface{}
+				if typed {
+					value = rdfaItem(c)
+				} else {
+					value = rdfaValue(c)
+				}
+				for _, prop := range props {
+					addProperty(item, shortName(prop), value)
+				}
+			}
+			if !typed {
+				walk(c)
+			}
+		}
+	}
+	walk(n)
+	return item
+}
+
+func rdfaValue(n *html.Node) interface{} {
+	for _, key := range []string{"content", "resource", "href", "src"} {
+		if value, ok := attr(n, key); ok {
+			return value
+		}
+	}
+	return nodeText(n)
+}
+
+// addProperty stores a property value, turning repeated properties into lists
+func addProperty(item map[string]interface{}, name string, value interface{}) {
+	existing, ok := item[name]
+	if !ok {
+		item[name] = value
+		return
+	}
+	if list, isList := existing.([]interface{}); isList {
+		item[name] = append(list, value)
+		return
+	}
+	item[name] = []interface{}{existing, value}
+}
+
+func nodeText(n *html.Node) string {
+	var b strings.Builder
+	var walk func(*html.Node)
+	walk = func(node *html.Node) {
+		if node.Type == html.TextNode {
+			b.WriteString(node.Data)
+		}
+		for c := node.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return strings.Join(strings.Fields(b.String()), " ")
+}
@@ -0,0 +1,256 @@
+package crawl
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/shwetakhatra/url-analyzer/models"
+)
+
+type object = map[string]interface{}
+
+func structuredDataOf(t *testing.T, page string) []models.StructuredData {
+	t.Helper()
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(page))
+	if err != nil {
+		t.Fatalf("parsing the page: %v", err)
+	}
+	result := &CrawlResult{}
+	extractStructuredData(doc, "url-1", result)
+	return result.StructuredData
+}
+
+func checkStructuredData(t *testing.T, got, want []models.StructuredData) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d items %+v, want %d", len(got), got, len(want))
+	}
+	for i := range want {
+		want[i].URLID = "url-1"
+		if want[i].Errors == nil {
+			want[i].Errors = []string{}
+		}
+		want[i].Valid = len(want[i].Errors) == 0
+		if !reflect.DeepEqual(got[i], want[i]) {
+			t.Errorf("item %d:\n got %+v\nwant %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestJSONLD(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   []models.StructuredData
+	}{
+		{
+			name:   "valid product with a nested offer",
+			script: `{"@context":"https://schema.org","@type":"Product","name":"Lamp","offers":{"@type":"Offer","price":"10","priceCurrency":"EUR"}}`,
+			want: []models.StructuredData{{
+				Format: FormatJSONLD, Type: "Product",
+				Data: object{"@context": "https://schema.org", "@type": "Product", "name": "Lamp",
+					"offers": object{"@type": "Offer", "price": "10", "priceCurrency": "EUR"}},
+			}},
+		},
+		{
+			name:   "nested item errors name their property",
+			script: `{"@type":"Product","name":"Lamp","offers":[{"@type":"Offer","price":"10"}]}`,
+			want: []models.StructuredData{{
+				Format: FormatJSONLD, Type: "Product",
+				Data:   object{"@type": "Product", "name": "Lamp", "offers": []interface{}{object{"@type": "Offer", "price": "10"}}},
+				Errors: []string{"offers: Offer is missing required property priceCurrency or priceSpecification"},
+			}},
+		},
+		{
+			name:   "empty values do not count",
+			script: `{"@type":"Person","name":"  "}`,
+			want: []models.StructuredData{{
+				Format: FormatJSONLD, Type: "Person",
+				Data:   object{"@type": "Person", "name": "  "},
+				Errors: []string{"Person is missing required property name"},
+			}},
+		},
+		{
+			name:   "graph and lists",
+			script: `{"@graph":[{"@type":"schema:WebSite","name":"Site","url":"https://example.com"},[{"@type":["https://schema.org/Person"],"name":"Ada"}]]}`,
+			want: []models.StructuredData{
+				{
+					Format: FormatJSONLD, Type: "WebSite",
+					Data: object{"@type": "schema:WebSite", "name": "Site", "url": "https://example.com"},
+				},
+				{
+					Format: FormatJSONLD, Type: "Person",
+					Data: object{"@type": []interface{}{"https://schema.org/Person"}, "name": "Ada"},
+				},
+			},
+		},
+		{
+			name:   "several types",
+			script: `{"@type":"Organization LocalBusiness","name":"Shop","url":"https://example.com"}`,
+			want: []models.StructuredData{{
+				Format: FormatJSONLD, Type: "LocalBusiness,Organization",
+				Data:   object{"@type": "Organization LocalBusiness", "name": "Shop", "url": "https://example.com"},
+				Errors: []string{"LocalBusiness is missing required property address"},
+			}},
+		},
+		{
+			name:   "untyped object",
+			script: `{"name":"nothing"}`,
+			want: []models.StructuredData{{
+				Format: FormatJSONLD,
+				Data:   object{"name": "nothing"},
+				Errors: []string{"item has no type"},
+			}},
+		},
+		{
+			name:   "no objects",
+			script: `["a", 1]`,
+			want: []models.StructuredData{{
+				Format: FormatJSONLD,
+				Errors: []string{"JSON-LD block contains no objects"},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page := `<script type="application/ld+json">` + tt.script + `</script>`
+			checkStructuredData(t, structuredDataOf(t, page), tt.want)
+		})
+	}
+}
+
+func TestJSONLDInvalid(t *testing.T) {
+	items := structuredDataOf(t, `<script type="application/ld+json">{"@type": "Person",</script>`)
+	if len(items) != 1 || items[0].Valid || len(items[0].Errors) != 1 ||
+		!strings.HasPrefix(items[0].Errors[0], "invalid JSON: ") {
+		t.Fatalf("got %+v, want one invalid JSON item", items)
+	}
+}
+
+func TestMicrodata(t *testing.T) {
+	tests := []struct {
+		name string
+		page string
+		want []models.StructuredData
+	}{
+		{
+			name: "property values by element",
+			page: `<div itemscope itemtype="https://schema.org/Event" itemid="#event">
+				<h1 itemprop="name">  Jazz
+				night </h1>
+				<meta itemprop="startDate" content="2025-06-01">
+				<time itemprop="endDate" datetime="2025-06-02">tomorrow</time>
+				<a itemprop="url" href="https://example.com/jazz">tickets</a>
+				<img itemprop="image" src="poster.png">
+				<data itemprop="capacity" value="120">a hundred and twenty</data>
+				<span><span itemprop="location">Hall</span></span>
+			</div>`,
+			want: []models.StructuredData{{
+				Format: FormatMicrodata, Type: "Event",
+				Data: object{"@type": "https://schema.org/Event", "@id": "#event", "name": "Jazz night",
+					"startDate": "2025-06-01", "endDate": "2025-06-02", "url": "https://example.com/jazz",
+					"image": "poster.png", "capacity": "120", "location": "Hall"},
+			}},
+		},
+		{
+			name: "nested item with repeated and shared properties",
+			page: `<div itemscope itemtype="https://schema.org/Product">
+				<span itemprop="name">Lamp</span>
+				<span itemprop="color">red</span><span itemprop="color">blue</span>
+				<div itemprop="offers" itemscope itemtype="https://schema.org/Offer">
+					<span itemprop="price priceSpecification">10</span>
+				</div>
+			</div>`,
+			want: []models.StructuredData{{
+				Format: FormatMicrodata, Type: "Product",
+				Data: object{"@type": "https://schema.org/Product", "name": "Lamp",
+					"color":  []interface{}{"red", "blue"},
+					"offers": object{"@type": "https://schema.org/Offer", "price": "10", "priceSpecification": "10"}},
+			}},
+		},
+		{
+			name: "nested item without itemprop stands alone",
+			page: `<div itemscope itemtype="https://schema.org/WebSite">
+				<span itemprop="name">Site</span>
+				<div itemscope itemtype="https://schema.org/Person"><span itemprop="name">Ada</span></div>
+			</div>`,
+			want: []models.StructuredData{
+				{
+					Format: FormatMicrodata, Type: "WebSite",
+					Data:   object{"@type": "https://schema.org/WebSite", "name": "Site"},
+					Errors: []string{"WebSite is missing required property url"},
+				},
+				{
+					Format: FormatMicrodata, Type: "Person",
+					Data: object{"@type": "https://schema.org/Person", "name": "Ada"},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkStructuredData(t, structuredDataOf(t, tt.page), tt.want)
+		})
+	}
+}
+
+func TestRDFa(t *testing.T) {
+	tests := []struct {
+		name string
+		page string
+		want []models.StructuredData
+	}{
+		{
+			name: "property values by attribute",
+			page: `<div vocab="https://schema.org/" typeof="Article" resource="#post">
+				<h1 property="headline">Hello</h1>
+				<span property="schema:datePublished" content="2025-01-01">New Year</span>
+				<a property="url" href="https://example.com/post">link</a>
+				<img property="image" src="cover.png">
+			</div>`,
+			want: []models.StructuredData{{
+				Format: FormatRDFa, Type: "Article",
+				Data: object{"@type": "Article", "@context": "https://schema.org/", "@id": "#post",
+					"headline": "Hello", "datePublished": "2025-01-01", "url": "https://example.com/post", "image": "cover.png"},
+				Errors: []string{"Article is missing required property author"},
+			}},
+		},
+		{
+			name: "nested item with a property",
+			page: `<div vocab="https://schema.org/" typeof="Review">
+				<div property="itemReviewed" typeof="Product"><span property="name">Lamp</span></div>
+				<span property="author">Ada</span>
+			</div>`,
+			want: []models.StructuredData{{
+				Format: FormatRDFa, Type: "Review",
+				Data: object{"@type": "Review", "@context": "https://schema.org/", "author": "Ada",
+					"itemReviewed": object{"@type": "Product", "name": "Lamp"}},
+				Errors: []string{"itemReviewed: Product is missing required property offers or review or aggregateRating"},
+			}},
+		},
+		{
+			name: "nested item without a property stands alone",
+			page: `<div vocab="https://schema.org/" typeof="WebSite">
+				<span property="name">Site</span><a property="url" href="/">home</a>
+				<div typeof="Person"><span property="name">Ada</span></div>
+			</div>`,
+			want: []models.StructuredData{
+				{
+					Format: FormatRDFa, Type: "WebSite",
+					Data: object{"@type": "WebSite", "@context": "https://schema.org/", "name": "Site", "url": "/"},
+				},
+				{
+					Format: FormatRDFa, Type: "Person",
+					Data: object{"@type": "Person", "name": "Ada"},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkStructuredData(t, structuredDataOf(t, tt.page), tt.want)
+		})
+	}
+}
@@ -0,0 +1,226 @@
+package crawl
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/shwetakhatra/url-analyzer/models"
+	"golang.org/x/net/publicsuffix"
+)
+
+// Third-party resource types
+const (
+	ThirdPartyScript = "script"
+	ThirdPartyIframe = "iframe"
+	ThirdPartyPixel  = "pixel"
+	ThirdPartyLink   = "link"
+)
+
+// CatalogEntry describes a known third-party vendor domain
+type CatalogEntry struct {
+	Domain   string `json:"domain"`
+	Vendor   string `json:"vendor"`
+	Category string `json:"category"`
+}
+
+// catalog categories whose vendors count as trackers
+var trackerCategories = map[string]bool{
+	"analytics":   true,
+	"advertising": true,
+	"tag_manager": true,
+	"marketing":   true,
+}
+
+//go:embed catalog/trackers.json
+var bundledCatalog []byte
+
+var catalog = mustParseCatalog(bundledCatalog)
+
+func mustParseCatalog(data []byte) map[string]CatalogEntry {
+	entries, err := parseCatalog(data)
+	if err != nil {
+		panic("invalid bundled tracker catalog: " + err.Error())
+	}
+	return entries
+}
+
+func parseCatalog(data []byte) (map[string]CatalogEntry, error) {
+	var list []CatalogEntry
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, err
+	}
+	entries := make(map[string]CatalogEntry, len(list))
+	for _, entry := range list {
+		entries[strings.ToLower(entry.Domain)] = entry
+	}
+	return entries, nil
+}
+
+// LoadTrackerCatalog merges the entries of a JSON catalog file over the
+// bundled catalog, so the list can be updated without a new build. It must
+// be called before the workers start.
+func LoadTrackerCatalog(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	entries, err := parseCatalog(data)
+	if err != nil {
+		return err
+	}
+	for domain, entry := range entries {
+		catalog[domain] = entry
+	}
+	return nil
+}
+
+// lookupCatalog finds the most specific catalog entry for a host, so that
+// fonts.googleapis.com wins over googleapis.com
+func lookupCatalog(host string) (CatalogEntry, bool) {
+	host = strings.ToLower(host)
+	for {
+		if entry, ok := catalog[host]; ok {
+			return entry, true
+		}
+		i := strings.IndexByte(host, '.')
+		if i < 0 {
+			return CatalogEntry{}, false
+		}
+		host = host[i+1:]
+	}
+}
+
+// link relations the browser loads a resource for; the others, such as
+// canonical, next or preconnect, point at documents or only open connections
+var loadingRels = map[string]bool{
+	"stylesheet":    true,
+	"icon":          true,
+	"preload":       true,
+	"modulepreload": true,
+	"manifest":      true,
+}
+
+// registrableDomain returns the eTLD+1 of a host, or the host itself for
+// IP addresses and hosts without a public suffix
+func registrableDomain(host string) string {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if domain, err := publicsuffix.EffectiveTLDPlusOne(host); err == nil {
+		return domain
+	}
+	return host
+}
+
+// analyzeThirdParties groups the external hosts the page loads code, frames,
+// pixels and linked resources from by registrable domain and matches them
+// against the tracker catalog
+func analyzeThirdParties(doc *goquery.Document, pageURL *url.URL, urlID string, result *CrawlResult) {
+	base := documentBase(doc, pageURL)
+	site := registrableDomain(pageURL.Hostname())
+	parties := map[string]*models.ThirdParty{}
+
+	record := func(raw, kind string) {
+		ref, err := base.Parse(strings.TrimSpace(raw))
+		if err != nil || (ref.Scheme != "http" && ref.Scheme != "https") || ref.Hostname() == "" {
+			return
+		}
+		host := strings.ToLower(ref.Hostname())
+		domain := registrableDomain(host)
+		if domain == site {
+			return
+		}
+		party, ok := parties[domain]
+		if !ok {
+			party = &models.ThirdParty{URLID: urlID, Domain: domain, Category: "unknown"}
+			parties[domain] = party
+		}
+		// hosts of one domain may belong to different vendors; a tracker
+		// names the party over a vendor that does not track
+		if entry, found := lookupCatalog(host); found {
+			tracker := trackerCategories[entry.Category]
+			if party.Vendor == "" || (tracker && !trackerCategories[party.Category]) {
+				party.Vendor, party.Category = entry.Vendor, entry.Category
+			}
+			party.Tracker = party.Tracker || tracker
+		}
+		if kind == ThirdPartyPixel {
+			// a tracking pixel tracks whatever the vendor otherwise does
+			party.Tracker = true
+		}
+		party.Requests++
+		party.Hosts = appendUnique(party.Hosts, host)
+		party.ResourceTypes = appendUnique(party.ResourceTypes, kind)
+	}
+
+	doc.Find("script[src]").Each(func(_ int, s *goquery.Selection) {
+		record(s.AttrOr("src", ""), ThirdPartyScript)
+	})
+	doc.Find("iframe[src], frame[src]").Each(func(_ int, s *goquery.Selection) {
+		record(s.AttrOr("src", ""), ThirdPartyIframe)
+	})
+	doc.Find("img[src]").Each(func(_ int, s *goquery.Selection) {
+		if isPixel(s) {
+			record(s.AttrOr("src", ""), ThirdPartyPixel)
+		}
+	})
+	doc.Find("noscript").Each(func(_ int, s *goquery.Selection) {
+		// noscript content is kept as raw text by the parser, so the
+		// fallback pixels inside it are parsed separately
+		fallback, err := goquery.NewDocumentFromReader(strings.NewReader(s.Text()))
+		if err != nil {
+			return
+		}
+		fallback.Find("img[src]").Each(func(_ int, img *goquery.Selection) {
+			if isPixel(img) {
+				record(img.AttrOr("src", ""), ThirdPartyPixel)
+			}
+		})
+	})
+	doc.Find("link[href]").Each(func(_ int, s *goquery.Selection) {
+		for _, rel := range strings.Fields(strings.ToLower(s.AttrOr("rel", ""))) {
+			if loadingRels[rel] {
+				record(s.AttrOr("href", ""), ThirdPartyLink)
+				return
+			}
+		}
+	})
+
+	domains := make([]string, 0, len(parties))
+	for domain := range parties {
+		domains = append(domains, domain)
+	}
+	sort.Strings(domains)
+	for _, domain := range domains {
+		party := parties[domain]
+		result.ThirdParties = append(result.ThirdParties, *party)
+		if party.Tracker {
+			result.TrackerCount++
+		}
+	}
+	result.ThirdPartyCount = len(domains)
+}
+
+// isPixel tells whether an image is a tracking pixel, i.e. tiny or hidden
+func isPixel(s *goquery.Selection) bool {
+	width, errW := strconv.Atoi(strings.TrimSuffix(s.AttrOr("width", ""), "px"))
+	height, errH := strconv.Atoi(strings.TrimSuffix(s.AttrOr("height", ""), "px"))
+	if errW == nil && errH == nil && width <= 1 && height <= 1 {
+		return true
+	}
+	style := strings.ReplaceAll(strings.ToLower(s.AttrOr("style", "")), " ", "")
+	return strings.Contains(style, "display:none") || strings.Contains(style, "visibility:hidden")
+}
+
+func appendUnique(values []string, value string) []string {
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
@@ -0,0 +1,114 @@
+package crawl
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/shwetakhatra/url-analyzer/models"
+)
+
+// CategoryTLS groups the findings about the certificate and TLS connection
+const CategoryTLS = "tls"
+
+// certExpiryWarning is how long before expiry a certificate gets flagged
+const certExpiryWarning = 30 * 24 * time.Hour
+
+// inspectTLS records the negotiated TLS version and the certificate chain of
+// an HTTPS response and flags expired, soon expiring, untrusted or
+// mismatching certificates.
+func inspectTLS(state *tls.ConnectionState, host string, urlID string, result *CrawlResult) {
+	if state == nil || len(state.PeerCertificates) == 0 {
+		return
+	}
+	add := func(code, severity, message string) {
+		result.Findings = append(result.Findings, models.Finding{
+			URLID:    urlID,
+			Category: CategoryTLS,
+			Code:     code,
+			Severity: severity,
+			Message:  message,
+		})
+	}
+
+	result.TLSVersion = tls.VersionName(state.Version)
+	if state.Version < tls.VersionTLS12 {
+		add("outdated_tls_version", models.SeverityWarning, result.TLSVersion+" is deprecated")
+	}
+
+	for i, cert := range state.PeerCertificates {
+		result.Certificates = append(result.Certificates, models.Certificate{
+			URLID:              urlID,
+			Position:           i,
+			Subject:            cert.Subject.String(),
+			Issuer:             cert.Issuer.String(),
+			SANs:               append(append([]string{}, cert.DNSNames...), ipStrings(cert)...),
+			SerialNumber:       cert.SerialNumber.String(),
+			NotBefore:          cert.NotBefore,
+			NotAfter:           cert.NotAfter,
+			KeyType:            keyType(cert),
+			SignatureAlgorithm: cert.SignatureAlgorithm.String(),
+		})
+	}
+
+	leaf := state.PeerCertificates[0]
+	notAfter := leaf.NotAfter
+	result.CertNotAfter = &notAfter
+	result.CertHostnameMatch = leaf.VerifyHostname(host) == nil
+
+	now := time.Now()
+	remaining := leaf.NotAfter.Sub(now)
+	switch {
+	case remaining <= 0:
+		add("cert_expired", models.SeverityError,
+			fmt.Sprintf("Certificate expired on %s", leaf.NotAfter.Format(time.DateOnly)))
+	case remaining <= certExpiryWarning:
+		add("cert_expiring_soon", models.SeverityWarning,
+			fmt.Sprintf("Certificate expires in %d days", int(math.Ceil(remaining.Hours()/24))))
+	}
+	if leaf.NotBefore.After(now) {
+		add("cert_not_yet_valid", models.SeverityError,
+			fmt.Sprintf("Certificate is not valid before %s", leaf.NotBefore.Format(time.DateOnly)))
+	}
+	if !result.CertHostnameMatch {
+		add("cert_hostname_mismatch", models.SeverityError, "Certificate is not valid for "+host)
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range state.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := leaf.Verify(x509.VerifyOptions{Intermediates: intermediates, CurrentTime: now})
+	var invalid x509.CertificateInvalidError
+	if err != nil && !(errors.As(err, &invalid) && invalid.Reason == x509.Expired) {
+		// expiry is already reported above with a clearer message
+		add("cert_untrusted", models.SeverityError, "Certificate chain is not trusted: "+err.Error())
+	}
+	result.CertValid = err == nil && result.CertHostnameMatch
+}
+
+func keyType(cert *x509.Certificate) string {
+	switch key := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return fmt.Sprintf("RSA %d", key.N.BitLen())
+	case *ecdsa.PublicKey:
+		return "ECDSA " + key.Curve.Params().Name
+	case ed25519.PublicKey:
+		return "Ed25519"
+	}
+	return cert.PublicKeyAlgorithm.String()
+}
+
+func ipStrings(cert *x509.Certificate) []string {
+	ips := make([]string, 0, len(cert.IPAddresses))
+	for _, ip := range cert.IPAddresses {
+		ips = append(ips, ip.String())
+	}
+	return ips
+}
@@ -2,6 +2,8 @@ package crawl
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"os"
 	"sync"
@@ -9,6 +11,7 @@ import (
 
 	"github.com/shwetakhatra/url-analyzer/database"
 	"github.com/shwetakhatra/url-analyzer/models"
+	"github.com/shwetakhatra/url-analyzer/utils"
 	"gorm.io/gorm"
 )
 
@@ -82,23 +85,294 @@ func StartWorker(ctx context.Context) {
 }
 
 func processURL(url models.URL) {
-	result, err := CrawlURL(url.URL, url.ID)
+	var (
+		result   *CrawlResult
+		snapshot *models.Snapshot
+		settings string
+		err      error
+	)
+	source := models.RunSourceFetch
+	if url.PendingSnapshotID != "" {
+		source = models.RunSourceSnapshot
+		result, snapshot, err = reprocessURL(url)
+		url.PendingSnapshotID = ""
+	} else {
+		var (
+			request *models.RequestOptions
+			recipe  *models.LoginRecipe
+		)
+		if request, recipe, err = requestSettings(url); err == nil {
+			opts := CrawlOptions{
+				CheckResources: url.CheckResources,
+				Disabled:       disabledAnalyzers(url),
+				Rules:          rulesFor(url),
+				Extractors:     extractorsFor(url),
+				Request:        request,
+				Login:          recipe,
+			}
+			settings = analysisSettings(url, opts)
+			if !settingsChanged(url.ID, settings) {
+				// only a page analyzed with the current settings may be
+				// skipped when the server reports it unchanged
+				opts.ETag, opts.LastModified = url.ETag, url.LastModified
+			}
+			if result, err = CrawlURL(url.URL, url.ID, opts); err == nil {
+				result.ProfileResults = crawlProfiles(url.URL, url.ID, url.Profiles, opts)
+			}
+		}
+		if err == nil {
+			if snapshot, err = saveSnapshot(url.ID, result.Page); err != nil {
+				debugLog("[Storage] Error saving snapshot for %s: %v", url.URL, err)
+				err = nil
+			}
+		}
+	}
+	if errors.Is(err, ErrNotModified) {
+		// nothing to analyze, the results of the last crawl still hold
+		url.Status = "done"
+		if err := saveResult(&url, "Status", "UpdatedAt"); err != nil {
+			debugLog("[DB] Error saving crawl result for %s: %v", url.URL, err)
+		}
+		recordUnchangedRun(url.ID, settings)
+		return
+	}
+	columns := append([]string{}, resultColumns...)
 	if err != nil {
 		url.Status = "error"
 		url.Error = err.Error()
+		// the next crawl has to be a full one
+		url.ETag, url.LastModified = "", ""
+		columns = append(columns, "ETag", "LastModified")
+		if result != nil {
+			// the request was refused over the certificate, whose
+			// inspection is all there is to keep
+			applyCertificate(&url, result)
+			if err := saveCertificateDetails(url.ID, result); err != nil {
+				debugLog("[DB] Error saving certificate details for %s: %v", url.URL, err)
+			}
+			result = nil
+		}
 	} else {
 		url.Status = "done"
-		url.HTMLVersion = result.HTMLVersion
-		url.Title = result.Title
-		url.HasLoginForm = result.HasLoginForm
-		url.H1Count = result.H1Count
-		url.H2Count = result.H2Count
-		url.InternalLinks = result.InternalLinks
-		url.ExternalLinks = result.ExternalLinks
-		url.BrokenLinks = result.BrokenLinkCount
+		url.Error = ""
+		applyResult(&url, result)
+		if source == models.RunSourceFetch {
+			url.ETag = result.Page.Header.Get("ETag")
+			url.LastModified = result.Page.Header.Get("Last-Modified")
+			columns = append(columns, "ETag", "LastModified")
+		}
+		if err := saveDetails(url.ID, result); err != nil {
+			debugLog("[DB] Error saving crawl details for %s: %v", url.URL, err)
+			// the details still hold the previous analysis
+			url.Error = "could not save the analysis details: " + err.Error()
+		}
 	}
 
-	if err := database.DB.Save(&url).Error; err != nil {
+	if err := saveResult(&url, columns...); err != nil {
 		debugLog("[DB] Error saving crawl result for %s: %v", url.URL, err)
 	}
+	recordRun(url.ID, source, settings, snapshot, result, err)
+}
+
+// reprocessURL analyzes the pending snapshot of a URL again, reusing the
+// link and resource checks of the last run instead of the network
+func reprocessURL(url models.URL) (*CrawlResult, *models.Snapshot, error) {
+	var snapshot models.Snapshot
+	if err := database.DB.Where("id = ? AND url_id = ?", url.PendingSnapshotID, url.ID).First(&snapshot).Error; err != nil {
+		return nil, nil, err
+	}
+	page, err := LoadSnapshot(context.Background(), snapshot)
+	if err != nil {
+		return nil, &snapshot, err
+	}
+	history, err := LoadCheckHistory(url.ID)
+	if err != nil {
+		return nil, &snapshot, err
+	}
+	result, err := AnalyzePage(page, url.ID, CrawlOptions{
+		CheckResources: url.CheckResources,
+		History:        history,
+		Disabled:       disabledAnalyzers(url),
+		Rules:          rulesFor(url),
+		Extractors:     extractorsFor(url),
+	})
+	if err != nil {
+		return nil, &snapshot, err
+	}
+	result.Page = page
+	// profiles are fetched live, so the last crawl's results stay
+	if err := database.DB.Where("url_id = ?", url.ID).Find(&result.ProfileResults).Error; err != nil {
+		return nil, &snapshot, err
+	}
+	return result, &snapshot, nil
+}
+
+// requestSettings decrypts the request options and the login recipe of a
+// URL, each nil if the URL has none
+func requestSettings(url models.URL) (*models.RequestOptions, *models.LoginRecipe, error) {
+	var (
+		options *models.RequestOptions
+		recipe  *models.LoginRecipe
+	)
+	if url.RequestOptions != "" {
+		options = &models.RequestOptions{}
+		if err := utils.DecryptJSON(url.RequestOptions, options); err != nil {
+			return nil, nil, fmt.Errorf("could not decrypt request options: %v", err)
+		}
+	}
+	if url.LoginRecipe != "" {
+		recipe = &models.LoginRecipe{}
+		if err := utils.DecryptJSON(url.LoginRecipe, recipe); err != nil {
+			return nil, nil, fmt.Errorf("could not decrypt login recipe: %v", err)
+		}
+	}
+	return options, recipe, nil
+}
+
+// disabledAnalyzers resolves the analyzer settings of a URL and its owner
+func disabledAnalyzers(url models.URL) map[string]bool {
+	var user models.User
+	if err := database.DB.Select("id", "analyzer_settings").First(&user, "id = ?", url.UserID).Error; err != nil {
+		debugLog("[DB] Error loading analyzer settings of user %s: %v", url.UserID, err)
+	}
+	disabled := map[string]bool{}
+	for name, enabled := range EnabledAnalyzers(user.AnalyzerSettings, url.AnalyzerSettings) {
+		if !enabled {
+			disabled[name] = true
+		}
+	}
+	return disabled
+}
+
+// resultColumns are the columns of a URL that every crawl writes. The
+// settings of the URL may change while it is crawled and are left alone, as
+// are the cache validators unless the crawl fetched new ones.
+var resultColumns = []string{
+	"Status", "Error", "PendingSnapshotID", "UpdatedAt",
+	"ContentType", "DocumentKind", "Charset", "HTMLVersion", "DocumentMode", "Title", "HasLoginForm",
+	"H1Count", "H2Count", "H3Count", "H4Count", "H5Count", "H6Count",
+	"InternalLinks", "ExternalLinks", "BrokenLinks", "BrokenFragments",
+	"MetaDescription", "CanonicalURL", "RobotsMeta", "XRobotsTag", "Viewport", "Lang",
+	"TitleLength", "DescriptionLength", "OpenGraph", "TwitterCard", "SEOScore",
+	"SecurityHeaders", "MissingSecurityHeaders", "SecurityScore", "SecurityGrade",
+	"TLSVersion", "CertNotAfter", "CertHostnameMatch", "CertValid",
+	"PageWeight", "RequestCount", "BrokenResources", "ThirdPartyCount", "TrackerCount",
+	"WordCount", "TextRatio", "SentenceCount", "AvgSentenceLength", "LongestSentence",
+	"ReadabilityScore", "TopTerms", "ContentHash", "SimHash",
+}
+
+// saveResult writes the given columns of a URL only
+func saveResult(url *models.URL, columns ...string) error {
+	return database.DB.Model(url).Select(columns).Updates(url).Error
+}
+
+// applyResult copies the page level results of an analysis onto the URL
+func applyResult(url *models.URL, result *CrawlResult) {
+	url.ContentType = result.ContentType
+	url.DocumentKind = result.DocumentKind
+	url.Charset = result.Charset
+	url.HTMLVersion = result.HTMLVersion
+	url.DocumentMode = result.DocumentMode
+	url.Title = result.Title
+	url.HasLoginForm = result.HasLoginForm
+	url.H1Count = result.H1Count
+	url.H2Count = result.H2Count
+	url.H3Count = result.H3Count
+	url.H4Count = result.H4Count
+	url.H5Count = result.H5Count
+	url.H6Count = result.H6Count
+	url.InternalLinks = result.InternalLinks
+	url.ExternalLinks = result.ExternalLinks
+	url.BrokenLinks = result.BrokenLinkCount
+	url.BrokenFragments = result.BrokenFragmentCount
+	url.MetaDescription = result.MetaDescription
+	url.CanonicalURL = result.CanonicalURL
+	url.RobotsMeta = result.RobotsMeta
+	url.XRobotsTag = result.XRobotsTag
+	url.Viewport = result.Viewport
+	url.Lang = result.Lang
+	url.TitleLength = result.TitleLength
+	url.DescriptionLength = result.DescriptionLength
+	url.OpenGraph = result.OpenGraph
+	url.TwitterCard = result.TwitterCard
+	url.SEOScore = result.SEOScore
+	url.SecurityHeaders = result.SecurityHeaders
+	url.MissingSecurityHeaders = result.MissingSecurityHeaders
+	url.SecurityScore = result.SecurityScore
+	url.SecurityGrade = result.SecurityGrade
+	applyCertificate(url, result)
+	url.PageWeight = result.PageWeight
+	url.RequestCount = result.RequestCount
+	url.BrokenResources = result.BrokenResourceCount
+	url.ThirdPartyCount = result.ThirdPartyCount
+	url.TrackerCount = result.TrackerCount
+	url.WordCount = result.WordCount
+	url.TextRatio = result.TextRatio
+	url.SentenceCount = result.SentenceCount
+	url.AvgSentenceLength = result.AvgSentenceLength
+	url.LongestSentence = result.LongestSentence
+	url.ReadabilityScore = result.ReadabilityScore
+	url.TopTerms = result.TopTerms
+	url.ContentHash = result.ContentHash
+	url.SimHash = result.SimHash
+}
+
+// applyCertificate copies the certificate results of an analysis onto the URL
+func applyCertificate(url *models.URL, result *CrawlResult) {
+	url.TLSVersion = result.TLSVersion
+	url.CertNotAfter = result.CertNotAfter
+	url.CertHostnameMatch = result.CertHostnameMatch
+	url.CertValid = result.CertValid
+}
+
+// saveCertificateDetails replaces the certificates and TLS findings of a URL
+// with those of a crawl that stopped at the certificate
+func saveCertificateDetails(urlID string, result *CrawlResult) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("url_id = ? AND category = ?", urlID, CategoryTLS).Delete(&models.Finding{}).Error; err != nil {
+			return err
+		}
+		if len(result.Findings) > 0 {
+			if err := tx.Create(&result.Findings).Error; err != nil {
+				return err
+			}
+		}
+		return replaceRows(tx, urlID, result.Certificates)
+	})
+}
+
+// saveDetails replaces the per-URL detail rows with those of a fresh crawl
+func saveDetails(urlID string, result *CrawlResult) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		replacements := []func() error{
+			func() error { return replaceRows(tx, urlID, result.BrokenLinks) },
+			func() error { return replaceRows(tx, urlID, result.Headings) },
+			func() error { return replaceRows(tx, urlID, result.Findings) },
+			func() error { return replaceRows(tx, urlID, result.StructuredData) },
+			func() error { return replaceRows(tx, urlID, result.Certificates) },
+			func() error { return replaceRows(tx, urlID, result.Resources) },
+			func() error { return replaceRows(tx, urlID, result.Forms) },
+			func() error { return replaceRows(tx, urlID, result.ThirdParties) },
+			func() error { return replaceRows(tx, urlID, result.AnalyzerResults) },
+			func() error { return replaceRows(tx, urlID, result.RuleResults) },
+			func() error { return replaceRows(tx, urlID, result.ExtractedValues) },
+			func() error { return replaceRows(tx, urlID, result.ProfileResults) },
+		}
+		for _, replace := range replacements {
+			if err := replace(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func replaceRows[T any](tx *gorm.DB, urlID string, rows []T) error {
+	if err := tx.Where("url_id = ?", urlID).Delete(new(T)).Error; err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	return tx.Create(&rows).Error
 }
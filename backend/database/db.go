@@ -26,7 +26,26 @@ func Connect() {
 		panic(fmt.Sprintf("Failed to connect database: %v", err))
 	}
 
-	db.AutoMigrate(&models.User{}, &models.URL{}, &models.BrokenLink{})
+	db.AutoMigrate(
+		&models.User{},
+		&models.URL{},
+		&models.BrokenLink{},
+		&models.Heading{},
+		&models.Finding{},
+		&models.StructuredData{},
+		&models.Certificate{},
+		&models.Resource{},
+		&models.Form{},
+		&models.ThirdParty{},
+		&models.Snapshot{},
+		&models.Run{},
+		&models.AnalyzerResult{},
+		&models.Rule{},
+		&models.RuleResult{},
+		&models.Extractor{},
+		&models.ExtractedValue{},
+		&models.ProfileResult{},
+	)
 
 	DB = db
 }
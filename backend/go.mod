@@ -6,7 +6,7 @@ toolchain go1.24.5
 
 require github.com/gin-gonic/gin v1.10.1
 
-require github.com/andybalholm/cascadia v1.3.3 // indirect
+require github.com/andybalholm/cascadia v1.3.3
 
 require (
 	filippo.io/edwards25519 v1.1.0 // indirect
@@ -42,7 +42,7 @@ require (
 	github.com/ugorji/go/codec v1.3.0 // indirect
 	golang.org/x/arch v0.18.0 // indirect
 	golang.org/x/crypto v0.39.0
-	golang.org/x/net v0.41.0 // indirect
+	golang.org/x/net v0.41.0
 	golang.org/x/sys v0.33.0 // indirect
 	golang.org/x/text v0.26.0 // indirect
 	google.golang.org/protobuf v1.36.6 // indirect
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AnalyzerResult is the outcome of one analyzer on the latest analysis of a
+// URL. Metrics are stored as the analyzer named them, so new analyzers need
+// no columns of their own.
+type AnalyzerResult struct {
+	ID           string                 `gorm:"type:char(36);primaryKey" json:"-"`
+	URLID        string                 `gorm:"type:char(36);not null;index" json:"-"`
+	URL          URL                    `gorm:"foreignKey:URLID;references:ID" json:"-"`
+	Analyzer     string                 `gorm:"size:50;index" json:"analyzer"`
+	Metrics      map[string]interface{} `gorm:"serializer:json;type:text" json:"metrics"`
+	FindingCount int                    `json:"finding_count"`
+	Error        string                 `gorm:"type:text" json:"error,omitempty"`
+	DurationMS   int64                  `json:"duration_ms"`
+	CreatedAt    time.Time              `json:"-"`
+	UpdatedAt    time.Time              `json:"-"`
+}
+
+func (result *AnalyzerResult) BeforeCreate(tx *gorm.DB) (err error) {
+	result.ID = uuid.New().String()
+	return
+}
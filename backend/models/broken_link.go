@@ -7,12 +7,20 @@ import (
 	"gorm.io/gorm"
 )
 
+// Broken link types
+const (
+	BrokenLinkTypeLink     = "link"
+	BrokenLinkTypeResource = "resource"
+	BrokenLinkTypeFragment = "fragment"
+)
+
 type BrokenLink struct {
 	ID        string     `gorm:"type:char(36);primaryKey"`
 	URLID     string     `gorm:"type:char(36);not null" json:"-"`
 	URL       URL        `gorm:"foreignKey:URLID;references:ID" json:"-"`
 	Link      string     `json:"link"`
 	Status    int        `json:"status"`
+	Type      string     `gorm:"size:20;default:link" json:"type"`
 	CreatedAt time.Time  `json:"-"`
 	UpdatedAt time.Time  `json:"-"`
 }
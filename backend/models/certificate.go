@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Certificate is one certificate of the chain presented by an HTTPS page,
+// Position 0 being the leaf
+type Certificate struct {
+	ID                 string    `gorm:"type:char(36);primaryKey" json:"-"`
+	URLID              string    `gorm:"type:char(36);not null;index" json:"-"`
+	URL                URL       `gorm:"foreignKey:URLID;references:ID" json:"-"`
+	Position           int       `json:"position"`
+	Subject            string    `gorm:"type:text" json:"subject"`
+	Issuer             string    `gorm:"type:text" json:"issuer"`
+	SANs               []string  `gorm:"serializer:json;type:text" json:"sans"`
+	SerialNumber       string    `json:"serial_number"`
+	NotBefore          time.Time `json:"not_before"`
+	NotAfter           time.Time `json:"not_after"`
+	KeyType            string    `json:"key_type"`
+	SignatureAlgorithm string    `json:"signature_algorithm"`
+	CreatedAt          time.Time `json:"-"`
+	UpdatedAt          time.Time `json:"-"`
+}
+
+func (cert *Certificate) BeforeCreate(tx *gorm.DB) (err error) {
+	cert.ID = uuid.New().String()
+	return
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Extractor is a named field a user pulls out of the pages, like a price
+// or an author. It takes the text or an attribute of the elements matching
+// its selector and, with a pattern, the first capture group of the regular
+// expression (or the whole match if it has no group). An extractor without
+// a URL pattern applies to every URL of its owner.
+type Extractor struct {
+	ID         string    `gorm:"type:char(36);primaryKey" json:"id"`
+	UserID     string    `gorm:"type:char(36);not null;index" json:"-"`
+	Name       string    `gorm:"size:100" json:"name"`
+	Selector   string    `gorm:"type:text" json:"selector"`
+	Attribute  string    `gorm:"size:100" json:"attribute,omitempty"`
+	Pattern    string    `gorm:"type:text" json:"pattern,omitempty"`
+	URLPattern string    `json:"url_pattern,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+func (extractor *Extractor) BeforeCreate(tx *gorm.DB) (err error) {
+	extractor.ID = uuid.New().String()
+	return
+}
+
+// ExtractedValue holds what an extractor found on the latest analysis of a
+// URL, one value per matching element
+type ExtractedValue struct {
+	ID          string    `gorm:"type:char(36);primaryKey" json:"-"`
+	URLID       string    `gorm:"type:char(36);not null;index" json:"-"`
+	URL         URL       `gorm:"foreignKey:URLID;references:ID" json:"-"`
+	ExtractorID string    `gorm:"type:char(36);index" json:"extractor_id"`
+	Name        string    `gorm:"size:100" json:"name"`
+	Values      []string  `gorm:"serializer:json;type:mediumtext" json:"values"`
+	Error       string    `gorm:"type:text" json:"error,omitempty"`
+	CreatedAt   time.Time `json:"-"`
+	UpdatedAt   time.Time `json:"-"`
+}
+
+func (value *ExtractedValue) BeforeCreate(tx *gorm.DB) (err error) {
+	value.ID = uuid.New().String()
+	return
+}
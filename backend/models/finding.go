@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Finding severities, from most to least serious
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+	SeverityNotice  = "notice"
+)
+
+// Finding is a single problem detected by one of the page checks
+type Finding struct {
+	ID        string    `gorm:"type:char(36);primaryKey" json:"id"`
+	URLID     string    `gorm:"type:char(36);not null;index" json:"-"`
+	URL       URL       `gorm:"foreignKey:URLID;references:ID" json:"-"`
+	Category  string    `gorm:"size:50;index" json:"category"`
+	Code      string    `gorm:"size:100" json:"code"`
+	Severity  string    `gorm:"size:20" json:"severity"`
+	Message   string    `gorm:"type:text" json:"message"`
+	Selector  string    `gorm:"type:text" json:"selector,omitempty"`
+	Element   string    `gorm:"size:50" json:"element,omitempty"`
+	Attribute string    `gorm:"size:50" json:"attribute,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"-"`
+}
+
+func (finding *Finding) BeforeCreate(tx *gorm.DB) (err error) {
+	finding.ID = uuid.New().String()
+	return
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Form kinds
+const (
+	FormLogin      = "login"
+	FormSignup     = "signup"
+	FormSearch     = "search"
+	FormNewsletter = "newsletter"
+	FormContact    = "contact"
+	FormPayment    = "payment"
+	FormUnknown    = "unknown"
+)
+
+type Form struct {
+	ID               string    `gorm:"type:char(36);primaryKey" json:"-"`
+	URLID            string    `gorm:"type:char(36);not null;index" json:"-"`
+	URL              URL       `gorm:"foreignKey:URLID;references:ID" json:"-"`
+	Position         int       `json:"position"`
+	Kind             string    `gorm:"size:20" json:"kind"`
+	Method           string    `gorm:"size:10" json:"method"`
+	Action           string    `gorm:"type:text" json:"action"`
+	FieldTypes       []string  `gorm:"serializer:json;type:text" json:"field_types"`
+	FieldNames       []string  `gorm:"serializer:json;type:text" json:"field_names"`
+	HasCSRFToken     bool      `json:"has_csrf_token"`
+	InsecureAction   bool      `json:"insecure_action"`
+	CredentialsInGET bool      `json:"credentials_in_get"`
+	Selector         string    `gorm:"type:text" json:"selector"`
+	CreatedAt        time.Time `json:"-"`
+	UpdatedAt        time.Time `json:"-"`
+}
+
+func (form *Form) BeforeCreate(tx *gorm.DB) (err error) {
+	form.ID = uuid.New().String()
+	return
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type Heading struct {
+	ID        string    `gorm:"type:char(36);primaryKey" json:"-"`
+	URLID     string    `gorm:"type:char(36);not null;index" json:"-"`
+	URL       URL       `gorm:"foreignKey:URLID;references:ID" json:"-"`
+	Level     int       `json:"level"`
+	Text      string    `gorm:"type:text" json:"text"`
+	Position  int       `json:"position"`
+	CreatedAt time.Time `json:"-"`
+	UpdatedAt time.Time `json:"-"`
+}
+
+func (heading *Heading) BeforeCreate(tx *gorm.DB) (err error) {
+	heading.ID = uuid.New().String()
+	return
+}
@@ -0,0 +1,23 @@
+package models
+
+// LoginRecipe describes how to log in to the site of a URL through its login
+// form before the page is fetched. Only the login page and the credentials
+// are required: the form defaults to the first login form of the page, the
+// username to its first text or email field and the password to its
+// password field. Like RequestOptions it is stored encrypted on the URL.
+type LoginRecipe struct {
+	LoginURL         string `json:"login_url"`
+	FormSelector     string `json:"form_selector,omitempty"`
+	UsernameSelector string `json:"username_selector,omitempty"`
+	PasswordSelector string `json:"password_selector,omitempty"`
+	Username         string `json:"username"`
+	Password         string `json:"password"`
+	// Fields sets further form fields by name, e.g. a tenant or a
+	// "remember me" checkbox
+	Fields map[string]string `json:"fields,omitempty"`
+	// SuccessSelector matches an element shown only after a successful
+	// login, FailureSelector one shown only after a failed one. Without
+	// them, a login fails when the login form is shown again.
+	SuccessSelector string `json:"success_selector,omitempty"`
+	FailureSelector string `json:"failure_selector,omitempty"`
+}
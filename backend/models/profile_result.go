@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Redirect is a response that sent the crawler on to another URL
+type Redirect struct {
+	URL        string `json:"url"`
+	StatusCode int    `json:"status_code"`
+}
+
+// ProfileResult is what the page of a URL looked like when fetched with
+// the user agent of a profile on its latest crawl
+type ProfileResult struct {
+	ID            string     `gorm:"type:char(36);primaryKey" json:"-"`
+	URLID         string     `gorm:"type:char(36);not null;index" json:"-"`
+	URL           URL        `gorm:"foreignKey:URLID;references:ID" json:"-"`
+	Profile       string     `gorm:"size:30" json:"profile"`
+	UserAgent     string     `gorm:"type:text" json:"user_agent"`
+	StatusCode    int        `json:"status_code"`
+	FinalURL      string     `gorm:"type:text" json:"final_url"`
+	Redirects     []Redirect `gorm:"serializer:json;type:text" json:"redirects"`
+	Title         string     `gorm:"type:text" json:"title"`
+	Headings      []string   `gorm:"serializer:json;type:text" json:"headings"`
+	InternalLinks int        `json:"internal_links"`
+	ExternalLinks int        `json:"external_links"`
+	ContentLength int        `json:"content_length"`
+	WordCount     int        `json:"word_count"`
+	ContentHash   string     `gorm:"size:64" json:"content_hash"`
+	Error         string     `gorm:"type:text" json:"error,omitempty"`
+	FetchedAt     time.Time  `json:"fetched_at"`
+	CreatedAt     time.Time  `json:"-"`
+	UpdatedAt     time.Time  `json:"-"`
+}
+
+func (result *ProfileResult) BeforeCreate(tx *gorm.DB) (err error) {
+	result.ID = uuid.New().String()
+	return
+}
@@ -0,0 +1,23 @@
+package models
+
+// RequestOptions are sent along with the request for the page of a URL,
+// e.g. credentials for a staging server or a cookie that skips a consent
+// wall. They are stored encrypted on the URL and never returned by the API.
+type RequestOptions struct {
+	Headers   map[string]string `json:"headers,omitempty"`
+	Cookies   map[string]string `json:"cookies,omitempty"`
+	BasicAuth *BasicAuth        `json:"basic_auth,omitempty"`
+	// ApplyToLinks also sends the options when checking links to the same
+	// host as the page
+	ApplyToLinks bool `json:"apply_to_links"`
+}
+
+type BasicAuth struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// Empty tells whether the options would not change a request
+func (o *RequestOptions) Empty() bool {
+	return o == nil || (len(o.Headers) == 0 && len(o.Cookies) == 0 && o.BasicAuth == nil)
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Resource is a subresource referenced by a page. Status, Size and
+// ContentType are only filled in when resource checks are enabled.
+type Resource struct {
+	ID          string    `gorm:"type:char(36);primaryKey" json:"-"`
+	URLID       string    `gorm:"type:char(36);not null;index" json:"-"`
+	URL         URL       `gorm:"foreignKey:URLID;references:ID" json:"-"`
+	Type        string    `gorm:"size:20" json:"type"`
+	Link        string    `gorm:"type:text" json:"link"`
+	Checked     bool      `json:"checked"`
+	Status      int       `json:"status"`
+	Size        int64     `json:"size"`
+	ContentType string    `json:"content_type"`
+	CreatedAt   time.Time `json:"-"`
+	UpdatedAt   time.Time `json:"-"`
+}
+
+func (resource *Resource) BeforeCreate(tx *gorm.DB) (err error) {
+	resource.ID = uuid.New().String()
+	return
+}
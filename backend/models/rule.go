@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Rule assertions
+const (
+	RuleExists    = "exists"
+	RuleNotExists = "not_exists"
+	RuleCount     = "count"
+)
+
+// Rule is a page standard defined by a user: a CSS selector and what the
+// number of elements it matches has to be. A rule applies to the URLs it
+// lists and to every URL matching its pattern, in which * stands for any
+// run of characters.
+type Rule struct {
+	ID         string    `gorm:"type:char(36);primaryKey" json:"id"`
+	UserID     string    `gorm:"type:char(36);not null;index" json:"-"`
+	Name       string    `gorm:"size:100" json:"name"`
+	Selector   string    `gorm:"type:text" json:"selector"`
+	Assertion  string    `gorm:"size:20" json:"assertion"`
+	Operator   string    `gorm:"size:2" json:"operator,omitempty"`
+	Value      int       `json:"value"`
+	Severity   string    `gorm:"size:20" json:"severity"`
+	URLPattern string    `json:"url_pattern,omitempty"`
+	URLIDs     []string  `gorm:"serializer:json;type:text" json:"url_ids"`
+	Enabled    bool      `json:"enabled"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+func (rule *Rule) BeforeCreate(tx *gorm.DB) (err error) {
+	rule.ID = uuid.New().String()
+	return
+}
+
+// RuleResult is the outcome of a rule on the latest analysis of a URL
+type RuleResult struct {
+	ID        string    `gorm:"type:char(36);primaryKey" json:"-"`
+	URLID     string    `gorm:"type:char(36);not null;index" json:"-"`
+	URL       URL       `gorm:"foreignKey:URLID;references:ID" json:"-"`
+	RuleID    string    `gorm:"type:char(36);index" json:"rule_id"`
+	Name      string    `gorm:"size:100" json:"name"`
+	Passed    bool      `json:"passed"`
+	Count     int       `json:"count"`
+	Message   string    `gorm:"type:text" json:"message"`
+	CreatedAt time.Time `json:"-"`
+	UpdatedAt time.Time `json:"-"`
+}
+
+func (result *RuleResult) BeforeCreate(tx *gorm.DB) (err error) {
+	result.ID = uuid.New().String()
+	return
+}
@@ -0,0 +1,115 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Run sources
+const (
+	RunSourceFetch    = "fetch"
+	RunSourceSnapshot = "snapshot"
+)
+
+// RunStatusUnchanged marks a run whose page the server reported as not
+// modified since the last crawl; it was not analyzed again
+const RunStatusUnchanged = "unchanged"
+
+// Run is one analysis version of a URL, either of a fresh fetch or of a
+// stored snapshot. The detail rows of the URL always belong to its latest
+// run; the run keeps a summary of its results, its main text, a fingerprint
+// of the settings it was analyzed with and what changed since the previous
+// successful run.
+type Run struct {
+	ID         string         `gorm:"type:char(36);primaryKey" json:"id"`
+	URLID      string         `gorm:"type:char(36);not null;index;uniqueIndex:idx_run_version" json:"-"`
+	URL        URL            `gorm:"foreignKey:URLID;references:ID" json:"-"`
+	Version    int            `gorm:"uniqueIndex:idx_run_version" json:"version"`
+	Source     string         `gorm:"size:20" json:"source"`
+	SnapshotID string         `gorm:"type:char(36)" json:"snapshot_id"`
+	Status     string         `gorm:"size:20" json:"status"`
+	Error      string         `gorm:"type:text" json:"error,omitempty"`
+	Summary    RunSummary     `gorm:"serializer:json;type:mediumtext" json:"summary"`
+	Changes    *ChangeSummary `gorm:"serializer:json;type:text" json:"changes"`
+	MainText   string         `gorm:"type:mediumtext" json:"-"`
+	Settings   string         `gorm:"size:64" json:"-"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"-"`
+}
+
+// RunSummary is what a run found, kept to compare runs with each other
+type RunSummary struct {
+	Title           string       `json:"title"`
+	MetaDescription string       `json:"meta_description"`
+	CanonicalURL    string       `json:"canonical_url"`
+	RobotsMeta      string       `json:"robots_meta"`
+	Lang            string       `json:"lang"`
+	HTMLVersion     string       `json:"html_version"`
+	DocumentKind    string       `json:"document_kind"`
+	StatusCode      int          `json:"status_code"`
+	HeadingCounts   [6]int       `json:"heading_counts"`
+	InternalLinks   int          `json:"internal_links"`
+	ExternalLinks   int          `json:"external_links"`
+	BrokenLinks     int          `json:"broken_links"`
+	WordCount       int          `json:"word_count"`
+	ContentHash     string       `json:"content_hash"`
+	SEOScore        int          `json:"seo_score"`
+	SecurityScore   int          `json:"security_score"`
+	SecurityGrade   string       `json:"security_grade"`
+	PageWeight      int64        `json:"page_weight"`
+	ThirdPartyCount int          `json:"third_party_count"`
+	TrackerCount    int          `json:"tracker_count"`
+	Findings        []RunFinding `json:"findings"`
+	Headings        []string     `json:"headings"`
+	Links           []string     `json:"links"`
+	BrokenLinkURLs  []string     `json:"broken_link_urls"`
+	Rules           []RunRule    `json:"rules"`
+	// Extracted maps extractor names to the values they found
+	Extracted map[string][]string `json:"extracted"`
+	// Metrics holds the metrics of every analyzer by analyzer name
+	Metrics map[string]map[string]interface{} `json:"metrics,omitempty"`
+}
+
+// ChangeSummary counts what changed from one run to another
+type ChangeSummary struct {
+	ComparedTo       int      `json:"compared_to"`
+	Fields           []string `json:"fields"`
+	HeadingsAdded    int      `json:"headings_added"`
+	HeadingsRemoved  int      `json:"headings_removed"`
+	LinksAdded       int      `json:"links_added"`
+	LinksRemoved     int      `json:"links_removed"`
+	BrokenLinksAdded int      `json:"broken_links_added"`
+	BrokenLinksFixed int      `json:"broken_links_fixed"`
+	FindingsAdded    int      `json:"findings_added"`
+	FindingsResolved int      `json:"findings_resolved"`
+	RulesFailed      int      `json:"rules_failed"`
+	RulesFixed       int      `json:"rules_fixed"`
+	LinesAdded       int      `json:"lines_added"`
+	LinesRemoved     int      `json:"lines_removed"`
+	ContentChanged   bool     `json:"content_changed"`
+}
+
+// RunFinding identifies a finding of a run
+type RunFinding struct {
+	Category string `json:"category"`
+	Code     string `json:"code"`
+	Severity string `json:"severity"`
+	Selector string `json:"selector,omitempty"`
+	Message  string `json:"message"`
+}
+
+// RunRule is the outcome of a rule in a run
+type RunRule struct {
+	RuleID  string `json:"rule_id"`
+	Name    string `json:"name"`
+	Passed  bool   `json:"passed"`
+	Count   int    `json:"count"`
+	Message string `json:"message"`
+}
+
+func (run *Run) BeforeCreate(tx *gorm.DB) (err error) {
+	run.ID = uuid.New().String()
+	return
+}
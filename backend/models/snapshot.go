@@ -0,0 +1,39 @@
+package models
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Snapshot is an archived response of a crawl. The gzipped body lives in
+// blob storage under BlobKey; the headers and the DER encoded certificate
+// chain are kept here so that TLS can be inspected again on reanalysis.
+type Snapshot struct {
+	ID               string      `gorm:"type:char(36);primaryKey" json:"id"`
+	URLID            string      `gorm:"type:char(36);not null;index" json:"-"`
+	URL              URL         `gorm:"foreignKey:URLID;references:ID" json:"-"`
+	FinalURL         string      `gorm:"type:text" json:"final_url"`
+	StatusCode       int         `json:"status_code"`
+	Headers          http.Header `gorm:"serializer:json;type:text" json:"headers"`
+	ContentType      string      `json:"content_type"`
+	Size             int         `json:"size"`
+	CompressedSize   int         `json:"compressed_size"`
+	SHA256           string      `gorm:"size:64" json:"sha256"`
+	TLSVersion       uint16      `json:"-"`
+	PeerCertificates [][]byte    `gorm:"serializer:json;type:mediumtext" json:"-"`
+	BlobKey          string      `json:"-"`
+	FetchedAt        time.Time   `gorm:"index" json:"fetched_at"`
+	CreatedAt        time.Time   `json:"-"`
+	UpdatedAt        time.Time   `json:"-"`
+}
+
+// BeforeCreate keeps an ID assigned up front, as the blob key is derived from it
+func (snapshot *Snapshot) BeforeCreate(tx *gorm.DB) (err error) {
+	if snapshot.ID == "" {
+		snapshot.ID = uuid.New().String()
+	}
+	return
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// StructuredData is one JSON-LD, microdata or RDFa item found on a page
+type StructuredData struct {
+	ID        string                 `gorm:"type:char(36);primaryKey" json:"id"`
+	URLID     string                 `gorm:"type:char(36);not null;index" json:"-"`
+	URL       URL                    `gorm:"foreignKey:URLID;references:ID" json:"-"`
+	Format    string                 `gorm:"size:20" json:"format"`
+	Type      string                 `json:"type"`
+	Data      map[string]interface{} `gorm:"serializer:json;type:mediumtext" json:"data"`
+	Valid     bool                   `json:"valid"`
+	Errors    []string               `gorm:"serializer:json;type:text" json:"errors"`
+	CreatedAt time.Time              `json:"-"`
+	UpdatedAt time.Time              `json:"-"`
+}
+
+func (item *StructuredData) BeforeCreate(tx *gorm.DB) (err error) {
+	item.ID = uuid.New().String()
+	return
+}
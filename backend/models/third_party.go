@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ThirdParty is a registrable domain, other than the page's own, that the
+// page loads scripts, frames, pixels or linked resources from
+type ThirdParty struct {
+	ID            string    `gorm:"type:char(36);primaryKey" json:"-"`
+	URLID         string    `gorm:"type:char(36);not null;index" json:"-"`
+	URL           URL       `gorm:"foreignKey:URLID;references:ID" json:"-"`
+	Domain        string    `gorm:"index" json:"domain"`
+	Vendor        string    `json:"vendor"`
+	Category      string    `gorm:"size:30" json:"category"`
+	Tracker       bool      `json:"tracker"`
+	Hosts         []string  `gorm:"serializer:json;type:text" json:"hosts"`
+	ResourceTypes []string  `gorm:"serializer:json;type:text" json:"resource_types"`
+	Requests      int       `json:"requests"`
+	CreatedAt     time.Time `json:"-"`
+	UpdatedAt     time.Time `json:"-"`
+}
+
+func (party *ThirdParty) BeforeCreate(tx *gorm.DB) (err error) {
+	party.ID = uuid.New().String()
+	return
+}
@@ -8,29 +8,96 @@ import (
 )
 
 type URL struct {
-	ID               string       `gorm:"primaryKey;type:char(36)"`
-	URL              string
-	Status           string
-	Title            string
-	HTMLVersion      string
-	H1Count          int
-	H2Count          int
-	H3Count          int
-	H4Count          int
-	H5Count          int
-	H6Count          int
-	InternalLinks    int
-	ExternalLinks    int
-	BrokenLinks      int
-	HasLoginForm     bool
-	Error          	 string
-	UserID           string       `gorm:"type:char(36);not null"`
-	BrokenLinkDetail []BrokenLink `gorm:"foreignKey:URLID"`
-	CreatedAt        time.Time
-	UpdatedAt        time.Time
+	ID                     string `gorm:"primaryKey;type:char(36)"`
+	URL                    string
+	Status                 string
+	Title                  string
+	HTMLVersion            string
+	DocumentMode           string
+	ContentType            string
+	DocumentKind           string
+	Charset                string
+	H1Count                int
+	H2Count                int
+	H3Count                int
+	H4Count                int
+	H5Count                int
+	H6Count                int
+	InternalLinks          int
+	ExternalLinks          int
+	BrokenLinks            int
+	BrokenFragments        int
+	HasLoginForm           bool
+	MetaDescription        string `gorm:"type:text"`
+	CanonicalURL           string
+	RobotsMeta             string
+	XRobotsTag             string
+	Viewport               string
+	Lang                   string `gorm:"size:35"`
+	TitleLength            int
+	DescriptionLength      int
+	OpenGraph              map[string]string `gorm:"serializer:json;type:text"`
+	TwitterCard            map[string]string `gorm:"serializer:json;type:text"`
+	SEOScore               int
+	SecurityHeaders        map[string]string `gorm:"serializer:json;type:text"`
+	MissingSecurityHeaders []string          `gorm:"serializer:json;type:text"`
+	SecurityScore          int
+	SecurityGrade          string `gorm:"size:2"`
+	TLSVersion             string
+	CertNotAfter           *time.Time
+	CertHostnameMatch      bool
+	CertValid              bool
+	CheckResources         bool
+	PendingSnapshotID      string          `gorm:"type:char(36)" json:"-"`
+	AnalyzerSettings       map[string]bool `gorm:"serializer:json;type:text"`
+	RequestOptions         string          `gorm:"type:text" json:"-"`
+	HasRequestOptions      bool            `gorm:"-"`
+	LoginRecipe            string          `gorm:"type:text" json:"-"`
+	HasLoginRecipe         bool            `gorm:"-"`
+	Profiles               []string        `gorm:"serializer:json;type:text"`
+	ETag                   string          `gorm:"column:etag"`
+	LastModified           string          `gorm:"size:64"`
+	PageWeight             int64
+	RequestCount           int
+	BrokenResources        int
+	ThirdPartyCount        int
+	TrackerCount           int
+	WordCount              int `gorm:"index"`
+	TextRatio              float64
+	SentenceCount          int
+	AvgSentenceLength      float64
+	LongestSentence        int
+	ReadabilityScore       float64
+	TopTerms               []TermCount `gorm:"serializer:json;type:text"`
+	ContentHash            string      `gorm:"size:64;index"`
+	SimHash                uint64
+	Error                  string
+	UserID                 string           `gorm:"type:char(36);not null"`
+	BrokenLinkDetail       []BrokenLink     `gorm:"foreignKey:URLID"`
+	StructuredData         []StructuredData `gorm:"foreignKey:URLID" json:",omitempty"`
+	Certificates           []Certificate    `gorm:"foreignKey:URLID" json:",omitempty"`
+	Resources              []Resource       `gorm:"foreignKey:URLID" json:",omitempty"`
+	Forms                  []Form           `gorm:"foreignKey:URLID" json:",omitempty"`
+	ThirdParties           []ThirdParty     `gorm:"foreignKey:URLID" json:",omitempty"`
+	AnalyzerResults        []AnalyzerResult `gorm:"foreignKey:URLID" json:",omitempty"`
+	ExtractedValues        []ExtractedValue `gorm:"foreignKey:URLID" json:",omitempty"`
+	CreatedAt              time.Time
+	UpdatedAt              time.Time
+}
+
+// TermCount is how often a term occurs in the main text of a page
+type TermCount struct {
+	Term  string `json:"term"`
+	Count int    `json:"count"`
 }
 
 func (url *URL) BeforeCreate(tx *gorm.DB) (err error) {
 	url.ID = uuid.New().String()
 	return
-}
\ No newline at end of file
+}
+
+func (url *URL) AfterFind(tx *gorm.DB) (err error) {
+	url.HasRequestOptions = url.RequestOptions != ""
+	url.HasLoginRecipe = url.LoginRecipe != ""
+	return
+}
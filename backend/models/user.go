@@ -10,6 +10,8 @@ type User struct {
 	Name     string `gorm:"size:100"`
 	Email    string `gorm:"unique"`
 	Password string
+	// AnalyzerSettings enables or disables analyzers for all URLs of the user
+	AnalyzerSettings map[string]bool `gorm:"serializer:json;type:text"`
 }
 
 func (user *User) BeforeCreate(tx *gorm.DB) (err error) {
@@ -0,0 +1,206 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const (
+	testBucket    = "snapshots"
+	testRegion    = "eu-central-1"
+	testAccessKey = "AKIDEXAMPLE"
+	testSecretKey = "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"
+)
+
+// s3Stub is an in-memory S3 bucket that only accepts requests signed with
+// the test credentials
+type s3Stub struct {
+	mu       sync.Mutex
+	objects  map[string][]byte
+	requests int
+}
+
+func newS3Stub(t *testing.T) (*s3Stub, *httptest.Server) {
+	stub := &s3Stub{objects: map[string][]byte{}}
+	server := httptest.NewServer(stub)
+	t.Cleanup(server.Close)
+	return stub, server
+}
+
+func (s *s3Stub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests++
+
+	body, _ := io.ReadAll(r.Body)
+	if msg := s.checkSignature(r, body); msg != "" {
+		http.Error(w, "<Error><Code>SignatureDoesNotMatch</Code><Message>"+msg+"</Message></Error>", http.StatusForbidden)
+		return
+	}
+	prefix := "/" + testBucket + "/"
+	if !strings.HasPrefix(r.URL.Path, prefix) {
+		http.Error(w, "<Error><Code>NoSuchBucket</Code></Error>", http.StatusNotFound)
+		return
+	}
+	key := strings.TrimPrefix(r.URL.Path, prefix)
+
+	switch r.Method {
+	case http.MethodPut:
+		s.objects[key] = body
+	case http.MethodGet:
+		data, ok := s.objects[key]
+		if !ok {
+			http.Error(w, "<Error><Code>NoSuchKey</Code></Error>", http.StatusNotFound)
+			return
+		}
+		w.Write(data)
+	case http.MethodDelete:
+		delete(s.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// checkSignature recomputes the SigV4 signature of a request the way S3
+// does and describes what is wrong with it, if anything
+func (s *s3Stub) checkSignature(r *http.Request, body []byte) string {
+	payloadHash := r.Header.Get("X-Amz-Content-Sha256")
+	if payloadHash != sha256Hex(body) {
+		return "payload hash does not match the body"
+	}
+	amzDate := r.Header.Get("X-Amz-Date")
+	if len(amzDate) != len("20060102T150405Z") {
+		return "missing X-Amz-Date"
+	}
+	date := amzDate[:8]
+
+	auth := r.Header.Get("Authorization")
+	fields := map[string]string{}
+	for _, part := range strings.Split(strings.TrimPrefix(auth, "AWS4-HMAC-SHA256 "), ", ") {
+		if name, value, ok := strings.Cut(part, "="); ok {
+			fields[name] = value
+		}
+	}
+	scope := date + "/" + testRegion + "/s3/aws4_request"
+	if fields["Credential"] != testAccessKey+"/"+scope {
+		return "unexpected credential " + fields["Credential"]
+	}
+	if fields["SignedHeaders"] != "host;x-amz-content-sha256;x-amz-date" {
+		return "unexpected signed headers " + fields["SignedHeaders"]
+	}
+
+	canonicalRequest := strings.Join([]string{
+		r.Method,
+		r.URL.EscapedPath(),
+		r.URL.RawQuery,
+		"host:" + r.Host,
+		"x-amz-content-sha256:" + payloadHash,
+		"x-amz-date:" + amzDate,
+		"",
+		fields["SignedHeaders"],
+		payloadHash,
+	}, "\n")
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+	key := []byte("AWS4" + testSecretKey)
+	for _, part := range []string{date, testRegion, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	if want := hex.EncodeToString(hmacSHA256(key, stringToSign)); fields["Signature"] != want {
+		return "signature does not match"
+	}
+	return ""
+}
+
+func newTestS3Store(t *testing.T, endpoint, secretKey string) *S3Store {
+	store, err := NewS3Store(S3Config{
+		Endpoint:  endpoint,
+		Region:    testRegion,
+		Bucket:    testBucket,
+		AccessKey: testAccessKey,
+		SecretKey: secretKey,
+		PathStyle: true,
+	})
+	if err != nil {
+		t.Fatalf("NewS3Store: %v", err)
+	}
+	return store
+}
+
+func TestS3StorePutGetDelete(t *testing.T) {
+	stub, server := newS3Stub(t)
+	store := newTestS3Store(t, server.URL, testSecretKey)
+	ctx := context.Background()
+
+	for _, key := range []string{"url-1/snapshot.html.gz", "url-2/a b+c=d.gz"} {
+		data := []byte("<html>" + key + "</html>")
+		if err := store.Put(ctx, key, data, "application/gzip"); err != nil {
+			t.Fatalf("Put(%q): %v", key, err)
+		}
+		got, err := store.Get(ctx, key)
+		if err != nil {
+			t.Fatalf("Get(%q): %v", key, err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Fatalf("Get(%q) = %q, want %q", key, got, data)
+		}
+		if err := store.Delete(ctx, key); err != nil {
+			t.Fatalf("Delete(%q): %v", key, err)
+		}
+		if _, err := store.Get(ctx, key); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("Get(%q) after Delete: got %v, want ErrNotFound", key, err)
+		}
+	}
+	if len(stub.objects) != 0 {
+		t.Fatalf("bucket still holds %d objects", len(stub.objects))
+	}
+}
+
+func TestS3StoreDeleteMissing(t *testing.T) {
+	_, server := newS3Stub(t)
+	store := newTestS3Store(t, server.URL, testSecretKey)
+	if err := store.Delete(context.Background(), "url-1/missing.gz"); err != nil {
+		t.Fatalf("Delete of a missing key: %v", err)
+	}
+}
+
+func TestS3StoreWrongSecretKey(t *testing.T) {
+	stub, server := newS3Stub(t)
+	store := newTestS3Store(t, server.URL, "not-the-secret-key")
+	err := store.Put(context.Background(), "url-1/snapshot.html.gz", []byte("data"), "")
+	if err == nil || !strings.Contains(err.Error(), "SignatureDoesNotMatch") {
+		t.Fatalf("Put with a wrong secret key: got %v, want a signature error", err)
+	}
+	if len(stub.objects) != 0 {
+		t.Fatalf("bucket stored an object sent with a wrong signature")
+	}
+}
+
+func TestS3StoreRejectsInvalidKeys(t *testing.T) {
+	stub, server := newS3Stub(t)
+	store := newTestS3Store(t, server.URL, testSecretKey)
+	ctx := context.Background()
+
+	for _, key := range []string{"", "/absolute", "../escape", "url-1/../../escape", "url-1//double", "url-1/./dot"} {
+		if err := store.Put(ctx, key, []byte("data"), ""); err == nil {
+			t.Errorf("Put(%q) succeeded, want an invalid key error", key)
+		}
+		if _, err := store.Get(ctx, key); err == nil || errors.Is(err, ErrNotFound) {
+			t.Errorf("Get(%q): got %v, want an invalid key error", key, err)
+		}
+		if err := store.Delete(ctx, key); err == nil {
+			t.Errorf("Delete(%q) succeeded, want an invalid key error", key)
+		}
+	}
+	if stub.requests != 0 {
+		t.Fatalf("invalid keys sent %d requests to the server", stub.requests)
+	}
+}
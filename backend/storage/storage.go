@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+)
+
+// ErrNotFound is returned by BlobStore.Get for keys that hold no blob
+var ErrNotFound = errors.New("blob not found")
+
+// BlobStore keeps opaque blobs under slash separated keys
+type BlobStore interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	Get(ctx context.Context, key string) ([]byte, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// Blobs is the store configured by Connect
+var Blobs BlobStore
+
+// Connect sets up Blobs from the environment. STORAGE_BACKEND selects "fs"
+// (the default, rooted at STORAGE_PATH) or "s3", which reads S3_ENDPOINT,
+// S3_REGION, S3_BUCKET, S3_ACCESS_KEY, S3_SECRET_KEY and S3_PATH_STYLE.
+func Connect() {
+	switch backend := os.Getenv("STORAGE_BACKEND"); backend {
+	case "", "fs":
+		root := os.Getenv("STORAGE_PATH")
+		if root == "" {
+			root = "data/blobs"
+		}
+		Blobs = NewFSStore(root)
+	case "s3":
+		store, err := NewS3Store(S3Config{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			Region:    os.Getenv("S3_REGION"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+			PathStyle: os.Getenv("S3_PATH_STYLE") == "true",
+		})
+		if err != nil {
+			panic(fmt.Sprintf("Failed to configure blob storage: %v", err))
+		}
+		Blobs = store
+	default:
+		panic(fmt.Sprintf("Unknown STORAGE_BACKEND %q", backend))
+	}
+}
+
+// validKey rejects keys that could escape the store, such as ../ segments
+func validKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || path.Clean(key) != key || strings.HasPrefix(key, "..") {
+		return fmt.Errorf("invalid blob key %q", key)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+)
+
+// ErrNoEncryptionKey is returned when ENCRYPTION_KEY is not set or is not a
+// 32 byte key, given as hex or base64
+var ErrNoEncryptionKey = errors.New("ENCRYPTION_KEY must be a 32 byte key in hex or base64")
+
+var (
+	aeadOnce sync.Once
+	aead     cipher.AEAD
+)
+
+func encryptionCipher() (cipher.AEAD, error) {
+	aeadOnce.Do(func() {
+		raw := os.Getenv("ENCRYPTION_KEY")
+		key, err := hex.DecodeString(raw)
+		if err != nil || len(key) != 32 {
+			key, err = base64.StdEncoding.DecodeString(raw)
+		}
+		if err != nil || len(key) != 32 {
+			return
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return
+		}
+		aead, _ = cipher.NewGCM(block)
+	})
+	if aead == nil {
+		return nil, ErrNoEncryptionKey
+	}
+	return aead, nil
+}
+
+// Encrypt seals data with AES-256-GCM and returns the nonce and ciphertext
+// as base64
+func Encrypt(data []byte) (string, error) {
+	gcm, err := encryptionCipher()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, data, nil)), nil
+}
+
+// Decrypt opens what Encrypt sealed
+func Decrypt(sealed string) ([]byte, error) {
+	gcm, err := encryptionCipher()
+	if err != nil {
+		return nil, err
+	}
+	raw, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) < gcm.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	return gcm.Open(nil, raw[:gcm.NonceSize()], raw[gcm.NonceSize():], nil)
+}
+
+// EncryptJSON encodes v as JSON and encrypts it
+func EncryptJSON(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return Encrypt(data)
+}
+
+// DecryptJSON decrypts what EncryptJSON produced into v
+func DecryptJSON(sealed string, v interface{}) error {
+	data, err := Decrypt(sealed)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}